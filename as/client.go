@@ -0,0 +1,352 @@
+package as
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/bingxueshuang/gnap/models"
+	"golang.org/x/exp/slices"
+)
+
+// ErrInvalidClientRecord is returned when a client record is malformed.
+var ErrInvalidClientRecord = errors.New("invalid client record")
+
+// ClientRecord is a client instance, or a class of client instances,
+// known to the AS. The restrictions apply only when set: a record
+// without StartModes allows every start mode, and so on.
+type ClientRecord struct {
+	// ID is the instance identifier, by which the client instance is
+	// passed by reference, or the class identifier for a class record.
+	ID string `json:"id"`
+	// Key is the key of the client instance. Class records have none,
+	// as each instance of the class presents its own key.
+	Key     *models.ClientKey     `json:"key,omitempty"`
+	ClassID string                `json:"class_id,omitempty"`
+	Display *models.ClientDisplay `json:"display,omitempty"`
+
+	StartModes  []models.StartMode `json:"start_modes,omitempty"`
+	FinishURIs  []string           `json:"finish_uris,omitempty"`
+	FinishMatch FinishMatch        `json:"finish_uri_match,omitempty"`
+	AccessTypes []string           `json:"access_types,omitempty"`
+}
+
+// Validate checks the client record.
+func (rec ClientRecord) Validate() error {
+	if rec.ID == "" {
+		return fmt.Errorf("%w: missing id", ErrInvalidClientRecord)
+	}
+	switch rec.FinishMatch {
+	case "", MatchExact, MatchPrefix:
+	default:
+		return fmt.Errorf("%w: %s: unknown finish uri match %q", ErrInvalidClientRecord, rec.ID, rec.FinishMatch)
+	}
+	for _, raw := range rec.FinishURIs {
+		uri, err := url.Parse(raw)
+		if err == nil {
+			err = checkFinishURI(uri)
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrInvalidClientRecord, rec.ID, err)
+		}
+	}
+	if rec.Key == nil {
+		return nil
+	}
+	err := rec.Key.Validate()
+	if err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrInvalidClientRecord, rec.ID, err)
+	}
+	return nil
+}
+
+// Identity returns the identity of the client instance.
+func (rec ClientRecord) Identity() ClientIdentity {
+	id := ClientIdentity{InstanceID: rec.ID, ClassID: rec.ClassID}
+	if rec.Key != nil {
+		id.Key = *rec.Key
+	}
+	return id
+}
+
+// Allows checks the grant request against the restrictions of the
+// client. The finish uri is checked with [ValidateFinishURI] even if the
+// client has no registered finish uris. Access rights by reference are
+// not checked and should be expanded beforehand. The error wraps a
+// [models.GNAPError].
+func (rec ClientRecord) Allows(req models.GrantRequest) error {
+	if req.Interact != nil && len(rec.StartModes) > 0 {
+		for _, start := range req.Interact.Start {
+			if !slices.Contains(rec.StartModes, start.Mode) {
+				return models.GNAPError{Code: "invalid_request", Desc: fmt.Sprintf("start mode %q not allowed", start.Mode)}
+			}
+		}
+	}
+	if req.Interact != nil && req.Interact.Finish != nil {
+		var uri *url.URL
+		if req.Interact.Finish.URI != nil {
+			uri = req.Interact.Finish.URI.URL
+		}
+		err := ValidateFinishURI(uri, rec.FinishURIs, rec.FinishMatch)
+		if err != nil {
+			return fmt.Errorf("%w: %w", models.GNAPError{Code: "invalid_request", Desc: err.Error()}, err)
+		}
+	}
+	if len(rec.AccessTypes) == 0 {
+		return nil
+	}
+	for _, token := range requestedTokens(req) {
+		for _, right := range token.Access {
+			if right.Ref == "" && !slices.Contains(rec.AccessTypes, right.Type) {
+				return models.GNAPError{Code: "request_denied", Desc: fmt.Sprintf("access type %q not allowed", right.Type)}
+			}
+		}
+	}
+	return nil
+}
+
+// ClientRegistry looks up the client instances and classes known to the
+// AS by their identifier.
+type ClientRegistry interface {
+	Lookup(ctx context.Context, id string) (ClientRecord, error)
+}
+
+// MemoryClientRegistry is a concurrency-safe in-memory [ClientRegistry].
+// The zero value is ready to use.
+type MemoryClientRegistry struct {
+	mu      sync.RWMutex
+	clients map[string]ClientRecord
+}
+
+// Lookup implements [ClientRegistry] interface.
+func (r *MemoryClientRegistry) Lookup(ctx context.Context, id string) (ClientRecord, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	rec, ok := r.clients[id]
+	if !ok {
+		return ClientRecord{}, ErrNotFound
+	}
+	return rec, nil
+}
+
+// Register adds the client record, replacing any record of the same
+// identifier.
+func (r *MemoryClientRegistry) Register(rec ClientRecord) error {
+	err := rec.Validate()
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.clients == nil {
+		r.clients = make(map[string]ClientRecord)
+	}
+	r.clients[rec.ID] = rec
+	return nil
+}
+
+// Unregister removes the client record.
+func (r *MemoryClientRegistry) Unregister(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.clients, id)
+}
+
+// replace swaps all the records at once.
+func (r *MemoryClientRegistry) replace(clients map[string]ClientRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clients = clients
+}
+
+// FileClientRegistry is a [ClientRegistry] backed by a json file of the
+// form {"clients": [...]}. The file is read again on lookup whenever it
+// has been modified. If it cannot be read, lookups are answered from the
+// records read before, and the error is kept for [FileClientRegistry.Err].
+type FileClientRegistry struct {
+	path string
+	mem  MemoryClientRegistry
+
+	mu      sync.Mutex
+	modTime time.Time
+	err     error // error of the last reload, if any
+}
+
+// NewFileClientRegistry is the constructor for [FileClientRegistry]
+// with mandatory path of the clients file, which is read immediately.
+func NewFileClientRegistry(path string) (*FileClientRegistry, error) {
+	r := &FileClientRegistry{path: path}
+	err := r.Reload()
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// clientFile is the layout of the clients file.
+type clientFile struct {
+	Clients []ClientRecord `json:"clients"`
+}
+
+// Reload reads the clients file if it has been modified since it was
+// last read. On failure the records read before are kept, and a file
+// that cannot be parsed is not read again until it is modified.
+func (r *FileClientRegistry) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	info, err := os.Stat(r.path)
+	if err != nil {
+		r.err = err
+		return err
+	}
+	if info.ModTime().Equal(r.modTime) {
+		return r.err
+	}
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		r.err = err
+		return err
+	}
+	r.modTime = info.ModTime()
+	r.err = r.parse(data)
+	return r.err
+}
+
+// parse replaces the records with those of the clients file.
+func (r *FileClientRegistry) parse(data []byte) error {
+	var file clientFile
+	err := json.Unmarshal(data, &file)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidClientRecord, err)
+	}
+	clients := make(map[string]ClientRecord, len(file.Clients))
+	for _, rec := range file.Clients {
+		err = rec.Validate()
+		if err != nil {
+			return err
+		}
+		clients[rec.ID] = rec
+	}
+	r.mem.replace(clients)
+	return nil
+}
+
+// Err returns the error of the last reload, or nil if the records are
+// those of the current clients file.
+func (r *FileClientRegistry) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Lookup implements [ClientRegistry] interface. A failed reload does not
+// fail the lookup, which is answered from the records read before.
+func (r *FileClientRegistry) Lookup(ctx context.Context, id string) (ClientRecord, error) {
+	_ = r.Reload() // kept for Err
+	return r.mem.Lookup(ctx, id)
+}
+
+// ClientResolver resolves the client instance of a grant request into
+// the record of a known client. Clients passed by reference, presenting
+// a key by reference, or claiming a class identifier must be known to
+// the registry. Unknown clients are rejected with "invalid_client".
+type ClientResolver struct {
+	registry ClientRegistry
+	dynamic  bool
+}
+
+// NewClientResolver is the constructor for [ClientResolver] with
+// mandatory client registry and optional parameters.
+func NewClientResolver(registry ClientRegistry, options ...clientResolverOption) (r *ClientResolver, err error) {
+	r = &ClientResolver{registry: registry}
+	for _, setter := range options {
+		err = setter(r)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// clientResolverOption is functional parameter for client resolver
+// constructor.
+type clientResolverOption func(*ClientResolver) error
+
+// WithDynamicClients is an optional parameter for [NewClientResolver] to
+// accept client instances that present their key by value without a
+// class identifier, which are otherwise rejected as unknown. No
+// restrictions apply to them.
+func WithDynamicClients() clientResolverOption {
+	return func(r *ClientResolver) error {
+		r.dynamic = true
+		return nil
+	}
+}
+
+// Resolve returns the record of the client instance. For instances of a
+// registered class, the restrictions of the class apply to the key
+// presented by the instance, which keeps its own display information.
+func (r *ClientResolver) Resolve(ctx context.Context, client models.ClientInstance) (ClientRecord, error) {
+	unknown := models.GNAPError{Code: "invalid_client"}
+	var id string
+	switch {
+	case client.Ref != "":
+		id = client.Ref
+	case client.Key.Ref != "":
+		id = client.Key.Ref
+	case client.ClassID != "":
+		id = client.ClassID
+	case r.dynamic:
+		key := client.Key
+		return ClientRecord{Key: &key, Display: client.Display}, nil
+	default:
+		return ClientRecord{}, unknown
+	}
+	rec, err := r.registry.Lookup(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return ClientRecord{}, unknown
+	}
+	if err != nil {
+		return ClientRecord{}, err
+	}
+	if client.Ref != "" || client.Key.Ref != "" {
+		if rec.Key == nil {
+			return ClientRecord{}, unknown
+		}
+		return rec, nil
+	}
+	if rec.Key != nil {
+		return ClientRecord{}, unknown // an instance record, not a class
+	}
+	// an instance of the class, presenting its own key. As the class
+	// identifier is claimed by the instance, the display of the class is
+	// not lent to it.
+	key := client.Key
+	rec.ID = ""
+	rec.ClassID = client.ClassID
+	rec.Key = &key
+	rec.Display = client.Display
+	return rec, nil
+}
+
+// Admit resolves the client instance of the grant request, and checks the
+// request against the restrictions of the client, including its finish
+// uri. It is the first step of processing a grant request, before the
+// access is decided by the [Policy] with the identity of the returned
+// record.
+func (r *ClientResolver) Admit(ctx context.Context, req models.GrantRequest) (ClientRecord, error) {
+	rec, err := r.Resolve(ctx, req.Client)
+	if err != nil {
+		return ClientRecord{}, err
+	}
+	err = rec.Allows(req)
+	if err != nil {
+		return ClientRecord{}, err
+	}
+	return rec, nil
+}
@@ -0,0 +1,221 @@
+package as
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bingxueshuang/gnap/models"
+)
+
+func TestClientResolver_Resolve(t *testing.T) {
+	key := models.ClientKey{
+		Proof: models.ProofHTTPSig,
+		JWK:   json.RawMessage(`{"kty":"OKP","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`),
+	}
+	registry := &MemoryClientRegistry{}
+	_ = registry.Register(ClientRecord{ID: "7C7C4AZ9KHRS6X63AJAO", Key: &key, Display: &models.ClientDisplay{Name: "Photo App"}})
+	_ = registry.Register(ClientRecord{ID: "photo-app", StartModes: []models.StartMode{models.ModeRedirect}, Display: &models.ClientDisplay{Name: "Photo App"}})
+	tests := []struct {
+		name    string
+		dynamic bool
+		client  models.ClientInstance
+		want    string
+		display string
+		wantErr error
+	}{
+		{name: "reference", client: models.ClientInstance{Ref: "7C7C4AZ9KHRS6X63AJAO"}, want: "7C7C4AZ9KHRS6X63AJAO", display: "Photo App"},
+		{name: "key reference", client: models.ClientInstance{Key: models.ClientKey{Ref: "7C7C4AZ9KHRS6X63AJAO"}}, want: "7C7C4AZ9KHRS6X63AJAO", display: "Photo App"},
+		{name: "class", client: models.ClientInstance{Key: key, ClassID: "photo-app"}},
+		{name: "class display", client: models.ClientInstance{Key: key, ClassID: "photo-app", Display: &models.ClientDisplay{Name: "My Photos"}}, display: "My Photos"},
+		{name: "instance as class", client: models.ClientInstance{Key: key, ClassID: "7C7C4AZ9KHRS6X63AJAO"}, wantErr: models.ErrGInvalidClient},
+		{name: "unknown reference", client: models.ClientInstance{Ref: "unknown"}, wantErr: models.ErrGInvalidClient},
+		{name: "class without key", client: models.ClientInstance{Ref: "photo-app"}, wantErr: models.ErrGInvalidClient},
+		{name: "unknown class", client: models.ClientInstance{Key: key, ClassID: "unknown"}, wantErr: models.ErrGInvalidClient},
+		{name: "dynamic", dynamic: true, client: models.ClientInstance{Key: key}},
+		{name: "dynamic not allowed", client: models.ClientInstance{Key: key}, wantErr: models.ErrGInvalidClient},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var options []clientResolverOption
+			if tt.dynamic {
+				options = append(options, WithDynamicClients())
+			}
+			r, _ := NewClientResolver(registry, options...)
+			got, err := r.Resolve(context.Background(), tt.client)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ClientResolver.Resolve() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.ID != tt.want || got.Key == nil {
+				t.Errorf("ClientResolver.Resolve() = %+v, want id %q with key", got, tt.want)
+			}
+			var display string
+			if got.Display != nil {
+				display = got.Display.Name
+			}
+			if display != tt.display {
+				t.Errorf("ClientResolver.Resolve() display = %q, want %q", display, tt.display)
+			}
+		})
+	}
+}
+
+func TestClientRecord_Allows(t *testing.T) {
+	rec := ClientRecord{
+		ID:          "7C7C4AZ9KHRS6X63AJAO",
+		StartModes:  []models.StartMode{models.ModeRedirect},
+		FinishURIs:  []string{"https://client.example.net/return"},
+		AccessTypes: []string{"photo-api"},
+	}
+	finish := func(uri string) *models.IAFinish {
+		u, _ := models.ParseURL(uri)
+		return &models.IAFinish{Method: models.MethodRedirect, URI: &u, Nonce: "LKLTI25DK82FX4T4QFZC"}
+	}
+	access := func(typ string) *models.ATRequest {
+		return &models.ATRequest{Single: models.TokenRequest{Access: []models.AccessRight{{Type: typ}}}}
+	}
+	tests := []struct {
+		name     string
+		req      models.GrantRequest
+		wantCode string
+	}{
+		{
+			name: "allowed",
+			req: models.GrantRequest{
+				AccessToken: access("photo-api"),
+				Interact:    &models.IARequest{Start: []models.IAStart{{Mode: models.ModeRedirect}}, Finish: finish("https://client.example.net/return")},
+			},
+		},
+		{
+			name:     "start mode",
+			req:      models.GrantRequest{Interact: &models.IARequest{Start: []models.IAStart{{Mode: models.ModeCode}}}},
+			wantCode: "invalid_request",
+		},
+		{
+			name:     "finish uri",
+			req:      models.GrantRequest{Interact: &models.IARequest{Start: []models.IAStart{{Mode: models.ModeRedirect}}, Finish: finish("https://attacker.example/")}},
+			wantCode: "invalid_request",
+		},
+		{
+			name:     "insecure finish uri",
+			req:      models.GrantRequest{Interact: &models.IARequest{Start: []models.IAStart{{Mode: models.ModeRedirect}}, Finish: finish("http://client.example.net/return")}},
+			wantCode: "invalid_request",
+		},
+		{
+			name:     "access type",
+			req:      models.GrantRequest{AccessToken: access("financial-transaction")},
+			wantCode: "request_denied",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := rec.Allows(tt.req)
+			var gerr models.GNAPError
+			errors.As(err, &gerr)
+			if gerr.Code != tt.wantCode {
+				t.Errorf("ClientRecord.Allows() error = %v, want code %q", err, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestClientResolver_Admit(t *testing.T) {
+	key := models.ClientKey{
+		Proof: models.ProofHTTPSig,
+		JWK:   json.RawMessage(`{"kty":"OKP","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`),
+	}
+	registry := &MemoryClientRegistry{}
+	_ = registry.Register(ClientRecord{ID: "7C7C4AZ9KHRS6X63AJAO", Key: &key, FinishURIs: []string{"https://client.example.net/return/"}, FinishMatch: MatchPrefix})
+	r, _ := NewClientResolver(registry)
+	request := func(uri string) models.GrantRequest {
+		u, _ := models.ParseURL(uri)
+		return models.GrantRequest{
+			Client:   models.ClientInstance{Ref: "7C7C4AZ9KHRS6X63AJAO"},
+			Interact: &models.IARequest{Start: []models.IAStart{{Mode: models.ModeRedirect}}, Finish: &models.IAFinish{Method: models.MethodRedirect, URI: &u, Nonce: "LKLTI25DK82FX4T4QFZC"}},
+		}
+	}
+	tests := []struct {
+		name     string
+		req      models.GrantRequest
+		wantCode string
+	}{
+		{name: "allowed", req: request("https://client.example.net/return/123")},
+		{name: "dot segments", req: request("https://client.example.net/return/../admin"), wantCode: "invalid_request"},
+		{name: "unknown client", req: models.GrantRequest{Client: models.ClientInstance{Ref: "unknown"}}, wantCode: "invalid_client"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.Admit(context.Background(), tt.req)
+			var gerr models.GNAPError
+			errors.As(err, &gerr)
+			if gerr.Code != tt.wantCode {
+				t.Fatalf("ClientResolver.Admit() error = %v, want code %q", err, tt.wantCode)
+			}
+			if err == nil && got.ID != "7C7C4AZ9KHRS6X63AJAO" {
+				t.Errorf("ClientResolver.Admit() = %+v", got)
+			}
+		})
+	}
+}
+
+func TestFileClientRegistry_Lookup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clients.json")
+	write := func(data string, mod time.Time) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`{"clients": [{"id": "a", "class_id": "photo-app"}]}`, time.Unix(1700000000, 0))
+	r, err := NewFileClientRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if rec, err := r.Lookup(ctx, "a"); err != nil || rec.ClassID != "photo-app" {
+		t.Errorf("FileClientRegistry.Lookup() = %+v, %v", rec, err)
+	}
+	write(`{"clients": [{"id": "b"}]}`, time.Unix(1700000060, 0))
+	if _, err = r.Lookup(ctx, "a"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("FileClientRegistry.Lookup() error = %v, want %v", err, ErrNotFound)
+	}
+	if _, err = r.Lookup(ctx, "b"); err != nil {
+		t.Errorf("FileClientRegistry.Lookup() error = %v", err)
+	}
+	for i, data := range []string{
+		`{"clients": [{"class_id": "photo-app"}]}`,
+		`{"clients": [{"id": "c", "finish_uris": ["http://client.example.net/return"]}]}`,
+		`{"clients": [{"id": "c", "finish_uri_match": "regexp"}]}`,
+	} {
+		write(data, time.Unix(1700000120+int64(i), 0))
+		if err = r.Reload(); !errors.Is(err, ErrInvalidClientRecord) {
+			t.Errorf("FileClientRegistry.Reload() error = %v, want %v", err, ErrInvalidClientRecord)
+		}
+	}
+	write(`{"clients": [{"id": "c"`, time.Unix(1700000180, 0))
+	if _, err = r.Lookup(ctx, "b"); err != nil {
+		t.Errorf("FileClientRegistry.Lookup() after bad write error = %v", err)
+	}
+	if err = r.Err(); !errors.Is(err, ErrInvalidClientRecord) {
+		t.Errorf("FileClientRegistry.Err() = %v, want %v", err, ErrInvalidClientRecord)
+	}
+	// the broken file is not parsed again until it is modified
+	write(`{"clients": [{"id": "c"}]}`, time.Unix(1700000180, 0))
+	if _, err = r.Lookup(ctx, "c"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("FileClientRegistry.Lookup() error = %v, want %v", err, ErrNotFound)
+	}
+	write(`{"clients": [{"id": "c"}]}`, time.Unix(1700000240, 0))
+	if _, err = r.Lookup(ctx, "c"); err != nil || r.Err() != nil {
+		t.Errorf("FileClientRegistry.Lookup() error = %v, Err() = %v", err, r.Err())
+	}
+}
@@ -0,0 +1,287 @@
+package as
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bingxueshuang/gnap/models"
+	"github.com/bingxueshuang/gnap/subject"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"golang.org/x/exp/slices"
+)
+
+// Thumbprint computes the base64url encoded SHA-256 thumbprint of the
+// client key, that of the JWK or else of the certificate.
+func (c ClientIdentity) Thumbprint() (string, error) {
+	switch {
+	case len(c.Key.JWK) != 0:
+		k, err := jwk.ParseKey(c.Key.JWK)
+		if err != nil {
+			return "", err
+		}
+		sum, err := k.Thumbprint(crypto.SHA256)
+		if err != nil {
+			return "", err
+		}
+		return base64.RawURLEncoding.EncodeToString(sum), nil
+	case c.Key.CertS256 != "":
+		return c.Key.CertS256, nil
+	case c.Key.Cert != "":
+		der, err := base64.StdEncoding.DecodeString(c.Key.Cert)
+		if err != nil {
+			return "", err
+		}
+		sum := sha256.Sum256(der)
+		return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+	}
+	return "", fmt.Errorf("%w: missing key material", models.ErrMissingField)
+}
+
+// ConsentID identifies the client instance in consent records: by its
+// instance identifier when it is registered with the AS, along with its
+// key, or else by the thumbprint of its key. Consent keyed by class is
+// enabled with [WithClassConsent].
+func (c ClientIdentity) ConsentID() (string, error) {
+	if c.InstanceID != "" {
+		return "instance:" + c.InstanceID, nil
+	}
+	thumbprint, err := c.Thumbprint()
+	if err != nil {
+		return "", err
+	}
+	return "jkt:" + thumbprint, nil
+}
+
+// Consent records the access that the RO approved for a client.
+type Consent struct {
+	Owner     subject.ID
+	Client    string // as given by ClientIdentity.ConsentID, or "class:" and the class identifier
+	Access    []models.AccessRight
+	Subject   bool // release of subject information approved
+	GrantedAt time.Time
+	ExpiresAt time.Time // zero value for consent that does not expire
+}
+
+// Expired reports whether the consent has expired at the given time.
+func (c Consent) Expired(now time.Time) bool {
+	return !c.ExpiresAt.IsZero() && !now.Before(c.ExpiresAt)
+}
+
+// Covers reports whether the consent, at the given time, covers the
+// access and subject information released by the decision.
+func (c Consent) Covers(d Decision, now time.Time) bool {
+	if c.Expired(now) || (d.Subject && !c.Subject) {
+		return false
+	}
+	for _, access := range d.Access {
+		if !models.IsSubset(access, c.Access) {
+			return false
+		}
+	}
+	return true
+}
+
+// ConsentStore persists the consents given by resource owners. There is
+// at most one consent per owner and client.
+type ConsentStore interface {
+	Get(ctx context.Context, owner subject.ID, client string) (Consent, error)
+	Put(ctx context.Context, consent Consent) error
+	List(ctx context.Context, owner subject.ID) ([]Consent, error)
+	Revoke(ctx context.Context, owner subject.ID, client string) error
+}
+
+// MemoryConsentStore is a concurrency-safe in-memory [ConsentStore].
+// The zero value is ready to use.
+type MemoryConsentStore struct {
+	mu       sync.RWMutex
+	consents []Consent
+}
+
+// index returns the position of the consent of owner to client, or -1.
+// Caller must hold the lock.
+func (s *MemoryConsentStore) index(owner subject.ID, client string) int {
+	return slices.IndexFunc(s.consents, func(c Consent) bool {
+		return c.Client == client && subject.Equal(c.Owner, owner)
+	})
+}
+
+// Get implements [ConsentStore] interface.
+func (s *MemoryConsentStore) Get(ctx context.Context, owner subject.ID, client string) (Consent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	i := s.index(owner, client)
+	if i < 0 {
+		return Consent{}, ErrNotFound
+	}
+	return s.consents[i], nil
+}
+
+// Put implements [ConsentStore] interface.
+func (s *MemoryConsentStore) Put(ctx context.Context, consent Consent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.index(consent.Owner, consent.Client)
+	if i < 0 {
+		s.consents = append(s.consents, consent)
+		return nil
+	}
+	s.consents[i] = consent
+	return nil
+}
+
+// List implements [ConsentStore] interface.
+func (s *MemoryConsentStore) List(ctx context.Context, owner subject.ID) ([]Consent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []Consent
+	for _, c := range s.consents {
+		if subject.Equal(c.Owner, owner) {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+// Revoke implements [ConsentStore] interface.
+func (s *MemoryConsentStore) Revoke(ctx context.Context, owner subject.ID, client string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.index(owner, client)
+	if i < 0 {
+		return ErrNotFound
+	}
+	s.consents = slices.Delete(s.consents, i, i+1)
+	return nil
+}
+
+// ConsentPolicy is a [Policy] requiring that the RO has approved the
+// access decided by another policy. When a prior consent of the RO to
+// the client covers the decision, no interaction is needed. Otherwise
+// interaction is required, during which the AS records the approval of
+// the RO with Approve. Decisions for an unidentified RO are passed on as
+// is, as they do not release access of any particular RO.
+type ConsentPolicy struct {
+	policy  Policy
+	store   ConsentStore
+	ttl     time.Duration
+	classes bool
+	now     func() time.Time
+}
+
+// NewConsentPolicy is the constructor for [ConsentPolicy] with mandatory
+// underlying policy and consent store, and optional parameters.
+func NewConsentPolicy(policy Policy, store ConsentStore, options ...consentPolicyOption) (p *ConsentPolicy, err error) {
+	p = &ConsentPolicy{policy: policy, store: store, now: time.Now}
+	for _, setter := range options {
+		err = setter(p)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// consentPolicyOption is functional parameter for consent policy
+// constructor.
+type consentPolicyOption func(*ConsentPolicy) error
+
+// WithConsentTTL is an optional parameter for [NewConsentPolicy] to set
+// how long consent lasts. Zero, the default, keeps consent until revoked.
+func WithConsentTTL(ttl time.Duration) consentPolicyOption {
+	return func(p *ConsentPolicy) error {
+		if ttl < 0 {
+			return fmt.Errorf("negative consent ttl: %v", ttl)
+		}
+		p.ttl = ttl
+		return nil
+	}
+}
+
+// WithClassConsent is an optional parameter for [NewConsentPolicy] to
+// key the consent of instances of a class by the class identifier, so
+// that consent given to one instance of the class extends to the others.
+// The class identifier must have been verified against the registered
+// classes, as by the [ClientResolver]. Registered instances are still
+// keyed by their instance identifier.
+func WithClassConsent() consentPolicyOption {
+	return func(p *ConsentPolicy) error {
+		p.classes = true
+		return nil
+	}
+}
+
+// WithConsentClock is an optional parameter for [NewConsentPolicy] to set
+// the clock that the policy reads the current time from, as in tests.
+func WithConsentClock(now func() time.Time) consentPolicyOption {
+	return func(p *ConsentPolicy) error {
+		if now == nil {
+			return errors.New("missing clock")
+		}
+		p.now = now
+		return nil
+	}
+}
+
+// consentID identifies the client in consent records.
+func (p *ConsentPolicy) consentID(client ClientIdentity) (string, error) {
+	if p.classes && client.InstanceID == "" && client.ClassID != "" {
+		return "class:" + client.ClassID, nil
+	}
+	return client.ConsentID()
+}
+
+// Decide implements [Policy] interface.
+func (p *ConsentPolicy) Decide(ctx context.Context, req models.GrantRequest, client ClientIdentity, owner *Owner) (Decision, error) {
+	d, err := p.policy.Decide(ctx, req, client, owner)
+	if err != nil || owner == nil || d.Verdict != Grant {
+		return d, err
+	}
+	id, err := p.consentID(client)
+	if err != nil {
+		return denial("invalid_client"), nil
+	}
+	consent, err := p.store.Get(ctx, owner.ID, id)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return Decision{}, err
+	}
+	if err == nil && consent.Covers(d, p.now()) {
+		return d, nil
+	}
+	if req.Interact == nil {
+		return denial("user_denied"), nil
+	}
+	return Decision{Verdict: Interact}, nil
+}
+
+// Approve records that the RO approved the decision for the client,
+// extending any prior consent.
+func (p *ConsentPolicy) Approve(ctx context.Context, client ClientIdentity, owner Owner, d Decision) error {
+	id, err := p.consentID(client)
+	if err != nil {
+		return err
+	}
+	now := p.now()
+	consent, err := p.store.Get(ctx, owner.ID, id)
+	switch {
+	case errors.Is(err, ErrNotFound) || (err == nil && consent.Expired(now)):
+		consent = Consent{Owner: owner.ID, Client: id}
+	case err != nil:
+		return err
+	}
+	for _, access := range d.Access {
+		consent.Access = models.Union(consent.Access, access)
+	}
+	consent.Subject = consent.Subject || d.Subject
+	consent.GrantedAt = now
+	consent.ExpiresAt = time.Time{}
+	if p.ttl > 0 {
+		consent.ExpiresAt = now.Add(p.ttl)
+	}
+	return p.store.Put(ctx, consent)
+}
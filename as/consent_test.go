@@ -0,0 +1,148 @@
+package as
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bingxueshuang/gnap/models"
+	"github.com/bingxueshuang/gnap/subject"
+)
+
+func TestConsentPolicy_Decide(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+	read := models.AccessRight{Type: "photo-api", Actions: []string{"read"}}
+	write := models.AccessRight{Type: "photo-api", Actions: []string{"read", "write"}}
+	rules, err := NewRulePolicy([]Rule{{Access: []models.AccessRight{write}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &MemoryConsentStore{}
+	p, err := NewConsentPolicy(rules, store, WithConsentTTL(time.Hour), WithConsentClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	email, _ := subject.NewIDEmail("user@example.com")
+	owner := &Owner{ID: email}
+	client := ClientIdentity{Key: models.ClientKey{
+		Proof: models.ProofHTTPSig,
+		JWK:   json.RawMessage(`{"kty":"OKP","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`),
+	}}
+	interact := &models.IARequest{Start: []models.IAStart{{Mode: models.ModeRedirect}}}
+	request := func(access models.AccessRight, ia *models.IARequest) models.GrantRequest {
+		return models.GrantRequest{
+			AccessToken: &models.ATRequest{Single: models.TokenRequest{Access: []models.AccessRight{access}}},
+			Interact:    ia,
+		}
+	}
+	decide := func(req models.GrantRequest, owner *Owner) Verdict {
+		t.Helper()
+		d, err := p.Decide(ctx, req, client, owner)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d.Verdict
+	}
+	if got := decide(request(read, nil), nil); got != Grant {
+		t.Errorf("ConsentPolicy.Decide() unidentified = %v, want %v", got, Grant)
+	}
+	if got := decide(request(read, interact), owner); got != Interact {
+		t.Errorf("ConsentPolicy.Decide() without consent = %v, want %v", got, Interact)
+	}
+	if got := decide(request(read, nil), owner); got != Deny {
+		t.Errorf("ConsentPolicy.Decide() without consent nor interaction = %v, want %v", got, Deny)
+	}
+	err = p.Approve(ctx, client, *owner, Decision{Access: map[string][]models.AccessRight{"": {read}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := decide(request(read, interact), owner); got != Grant {
+		t.Errorf("ConsentPolicy.Decide() with consent = %v, want %v", got, Grant)
+	}
+	if got := decide(request(write, interact), owner); got != Interact {
+		t.Errorf("ConsentPolicy.Decide() beyond consent = %v, want %v", got, Interact)
+	}
+	now = now.Add(time.Hour)
+	if got := decide(request(read, interact), owner); got != Interact {
+		t.Errorf("ConsentPolicy.Decide() with expired consent = %v, want %v", got, Interact)
+	}
+	consents, _ := store.List(ctx, email)
+	if len(consents) != 1 {
+		t.Fatalf("MemoryConsentStore.List() = %v, want 1 consent", consents)
+	}
+	if err = store.Revoke(ctx, email, consents[0].Client); err != nil {
+		t.Errorf("MemoryConsentStore.Revoke() error = %v", err)
+	}
+	if err = store.Revoke(ctx, email, consents[0].Client); !errors.Is(err, ErrNotFound) {
+		t.Errorf("MemoryConsentStore.Revoke() error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestConsentPolicy_classes(t *testing.T) {
+	ctx := context.Background()
+	read := models.AccessRight{Type: "photo-api", Actions: []string{"read"}}
+	rules, _ := NewRulePolicy([]Rule{{Access: []models.AccessRight{read}}})
+	email, _ := subject.NewIDEmail("user@example.com")
+	owner := Owner{ID: email}
+	instance := func(x string) ClientIdentity {
+		return ClientIdentity{ClassID: "photo-app", Key: models.ClientKey{
+			Proof: models.ProofHTTPSig,
+			JWK:   json.RawMessage(`{"kty":"OKP","crv":"Ed25519","x":"` + x + `"}`),
+		}}
+	}
+	first := instance("11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo")
+	second := instance("VCpo2LMLhn6iWku8MKvSLg2ZAoC-nlOyPVQaO3FxVeQ")
+	req := models.GrantRequest{
+		AccessToken: &models.ATRequest{Single: models.TokenRequest{Access: []models.AccessRight{read}}},
+		Interact:    &models.IARequest{Start: []models.IAStart{{Mode: models.ModeRedirect}}},
+	}
+	for _, classes := range []bool{false, true} {
+		var options []consentPolicyOption
+		want := Interact
+		if classes {
+			options = append(options, WithClassConsent())
+			want = Grant
+		}
+		p, _ := NewConsentPolicy(rules, &MemoryConsentStore{}, options...)
+		if err := p.Approve(ctx, first, owner, Decision{Access: map[string][]models.AccessRight{"": {read}}}); err != nil {
+			t.Fatal(err)
+		}
+		d, err := p.Decide(ctx, req, second, &owner)
+		if err != nil || d.Verdict != want {
+			t.Errorf("ConsentPolicy.Decide() other instance with class consent %v = %v, %v, want %v", classes, d.Verdict, err, want)
+		}
+	}
+}
+
+func TestClientIdentity_ConsentID(t *testing.T) {
+	key := models.ClientKey{
+		Proof: models.ProofHTTPSig,
+		JWK:   json.RawMessage(`{"kty":"OKP","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`),
+	}
+	tests := []struct {
+		name    string
+		client  ClientIdentity
+		want    string
+		wantErr bool
+	}{
+		{name: "instance", client: ClientIdentity{InstanceID: "7C7C4AZ9KHRS6X63AJAO", Key: key}, want: "instance:7C7C4AZ9KHRS6X63AJAO"},
+		{name: "class", client: ClientIdentity{ClassID: "photo-app", Key: key}, want: "jkt:kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k"},
+		{name: "key", client: ClientIdentity{Key: key}, want: "jkt:kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k"},
+		{name: "cert thumbprint", client: ClientIdentity{Key: models.ClientKey{CertS256: "bwcK0esc3ACC3DB2Y5_lESsXE8o9ltc05O89jdN-dg2"}}, want: "jkt:bwcK0esc3ACC3DB2Y5_lESsXE8o9ltc05O89jdN-dg2"},
+		{name: "no key", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.client.ConsentID()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ClientIdentity.ConsentID() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ClientIdentity.ConsentID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,14 @@
+// Package as provides building blocks for a GNAP authorization server.
+//
+// The package does not impose a complete server. Instead it offers
+// storage interfaces with in-memory implementations, and [net/http]
+// handlers for the endpoints that the AS exposes, such as the token
+// introspection endpoint used by resource servers. A grant request is
+// first admitted by the [ClientResolver], which identifies the client
+// and checks the request, including its finish uri, against the
+// registered restrictions. The access to grant is then decided by a
+// [Policy], such as the declarative [RulePolicy]. A
+// [Limiter] guards the endpoints against clients polling too fast and
+// against guessing of user codes, and a [Sweeper] purges expired grants,
+// access tokens and limiter entries in the background.
+package as // import "github.com/bingxueshuang/gnap/as"
@@ -0,0 +1,112 @@
+package as
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// Errors of finish uri validation.
+var (
+	ErrInsecureFinishURI     = errors.New("finish uri must use https")
+	ErrFinishURIFragment     = errors.New("finish uri must not have a fragment")
+	ErrFinishURIDotSegment   = errors.New("finish uri must not have dot segments")
+	ErrUnregisteredFinishURI = errors.New("finish uri not registered")
+)
+
+// FinishMatch selects how the finish uri of a request is compared with
+// the finish uris registered for the client.
+type FinishMatch string
+
+// Matching of finish uris.
+const (
+	// MatchExact requires the finish uri to equal a registered uri.
+	MatchExact FinishMatch = "exact"
+	// MatchPrefix requires the finish uri to have the same scheme and
+	// host as a registered uri, and a path below its path. The query
+	// is not compared.
+	MatchPrefix FinishMatch = "prefix"
+)
+
+// ValidateFinishURI checks the finish uri of an interaction request, so
+// that the AS does not act as an open redirector. The uri must be
+// absolute, use https unless its host is a loopback address, and carry
+// no fragment nor "." and ".." path segments, which would let it escape
+// the registered path. If registered uris are given, the uri must match one of
+// them; the port of loopback uris is not compared, as native clients
+// listen on ephemeral ports. The zero value of match means [MatchExact].
+func ValidateFinishURI(uri *url.URL, registered []string, match FinishMatch) error {
+	err := checkFinishURI(uri)
+	if err != nil {
+		return err
+	}
+	if len(registered) == 0 {
+		return nil
+	}
+	ok := slices.ContainsFunc(registered, func(raw string) bool {
+		reg, err := url.Parse(raw)
+		return err == nil && matchFinishURI(uri, reg, match)
+	})
+	if !ok {
+		return ErrUnregisteredFinishURI
+	}
+	return nil
+}
+
+// checkFinishURI checks the scheme, fragment and path segments of the
+// finish uri.
+func checkFinishURI(uri *url.URL) error {
+	if uri == nil || !uri.IsAbs() || uri.Host == "" {
+		return fmt.Errorf("%w: not absolute", ErrInsecureFinishURI)
+	}
+	if uri.Fragment != "" || uri.RawFragment != "" {
+		return ErrFinishURIFragment
+	}
+	for _, segment := range strings.Split(uri.Path, "/") {
+		if segment == "." || segment == ".." {
+			return ErrFinishURIDotSegment
+		}
+	}
+	switch {
+	case uri.Scheme == "https":
+		return nil
+	case uri.Scheme == "http" && isLoopback(uri.Hostname()):
+		return nil
+	}
+	return ErrInsecureFinishURI
+}
+
+// matchFinishURI compares the finish uri with a registered uri.
+func matchFinishURI(uri, reg *url.URL, match FinishMatch) bool {
+	if uri.Scheme != reg.Scheme || uri.User != nil || reg.User != nil {
+		return false
+	}
+	if !strings.EqualFold(uri.Hostname(), reg.Hostname()) {
+		return false
+	}
+	if uri.Port() != reg.Port() && !isLoopback(uri.Hostname()) {
+		return false
+	}
+	if match != MatchPrefix {
+		return uri.EscapedPath() == reg.EscapedPath() && uri.RawQuery == reg.RawQuery
+	}
+	path, prefix := uri.EscapedPath(), reg.EscapedPath()
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	// the prefix must end at a path segment boundary
+	return len(path) == len(prefix) || strings.HasSuffix(prefix, "/") || path[len(prefix)] == '/'
+}
+
+// isLoopback reports whether host names the loopback interface.
+func isLoopback(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
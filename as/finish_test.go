@@ -0,0 +1,54 @@
+package as
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+)
+
+func TestValidateFinishURI(t *testing.T) {
+	registered := []string{
+		"https://client.example.net/return",
+		"https://client.example.net/app/?tenant=a",
+		"http://127.0.0.1:8080/callback",
+	}
+	tests := []struct {
+		name    string
+		uri     string
+		match   FinishMatch
+		wantErr error
+	}{
+		{name: "exact", uri: "https://client.example.net/return"},
+		{name: "exact with query", uri: "https://client.example.net/app/?tenant=a"},
+		{name: "exact other query", uri: "https://client.example.net/app/?tenant=b", wantErr: ErrUnregisteredFinishURI},
+		{name: "exact below path", uri: "https://client.example.net/return/123", wantErr: ErrUnregisteredFinishURI},
+		{name: "prefix below path", uri: "https://client.example.net/return/123", match: MatchPrefix},
+		{name: "prefix query", uri: "https://client.example.net/app/x?tenant=b", match: MatchPrefix},
+		{name: "prefix segment", uri: "https://client.example.net/returned", match: MatchPrefix, wantErr: ErrUnregisteredFinishURI},
+		{name: "prefix other host", uri: "https://client.example.net.attacker.example/return", match: MatchPrefix, wantErr: ErrUnregisteredFinishURI},
+		{name: "prefix userinfo", uri: "https://attacker@client.example.net/return", match: MatchPrefix, wantErr: ErrUnregisteredFinishURI},
+		{name: "loopback any port", uri: "http://127.0.0.1:53211/callback"},
+		{name: "http", uri: "http://client.example.net/return", wantErr: ErrInsecureFinishURI},
+		{name: "custom scheme", uri: "com.example.app:/return", wantErr: ErrInsecureFinishURI},
+		{name: "relative", uri: "/return", wantErr: ErrInsecureFinishURI},
+		{name: "fragment", uri: "https://client.example.net/return#x", wantErr: ErrFinishURIFragment},
+		{name: "prefix dot segments", uri: "https://client.example.net/return/../admin/steal", match: MatchPrefix, wantErr: ErrFinishURIDotSegment},
+		{name: "prefix escaped dot segments", uri: "https://client.example.net/return/%2e%2e/admin", match: MatchPrefix, wantErr: ErrFinishURIDotSegment},
+		{name: "current segment", uri: "https://client.example.net/./return", wantErr: ErrFinishURIDotSegment},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uri, err := url.Parse(tt.uri)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = ValidateFinishURI(uri, registered, tt.match)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ValidateFinishURI() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+	if err := ValidateFinishURI(&url.URL{Scheme: "http", Host: "localhost"}, nil, ""); err != nil {
+		t.Errorf("ValidateFinishURI() unregistered loopback error = %v, want nil", err)
+	}
+}
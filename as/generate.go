@@ -0,0 +1,57 @@
+package as
+
+import (
+	"sync/atomic"
+
+	"github.com/bingxueshuang/gnap/nonce"
+)
+
+// Generators of the unguessable values issued by the AS.
+var (
+	tokenValues atomic.Pointer[nonce.Generator]
+	nonces      atomic.Pointer[nonce.Generator]
+)
+
+func init() {
+	SetTokenGenerator(nil)
+	SetNonceGenerator(nil)
+}
+
+// SetTokenGenerator sets the generator of the values of access tokens
+// and continuation access tokens, for example one with a deterministic
+// source in tests. A nil generator restores the default of 40
+// characters drawn from [crypto/rand].
+func SetTokenGenerator(g *nonce.Generator) {
+	if g == nil {
+		g = nonce.MustNew(nonce.WithLength(40))
+	}
+	tokenValues.Store(g)
+}
+
+// SetNonceGenerator sets the generator of the nonces, interaction
+// references and identifiers issued by the AS. A nil generator restores
+// the default generator of [nonce.New].
+func SetNonceGenerator(g *nonce.Generator) {
+	if g == nil {
+		g = nonce.MustNew()
+	}
+	nonces.Store(g)
+}
+
+// NewTokenValue generates the value of a new access token or
+// continuation access token.
+func NewTokenValue() (string, error) {
+	return tokenValues.Load().Generate()
+}
+
+// NewNonce generates the nonce that the AS contributes to the
+// interaction finish hash.
+func NewNonce() (string, error) {
+	return nonces.Load().Generate()
+}
+
+// NewInteractRef generates the interaction reference returned to the
+// client instance when interaction finishes.
+func NewInteractRef() (string, error) {
+	return nonces.Load().Generate()
+}
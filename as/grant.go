@@ -0,0 +1,296 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/bingxueshuang/gnap/models"
+)
+
+// ErrExpired is returned when a grant, interaction or access token is
+// used after it has expired.
+var ErrExpired = errors.New("expired")
+
+// GrantRecord is the record of a grant request processed by the AS.
+// The grant may be continued with its continuation access token, and
+// the end user may interact at the interaction urls identified by
+// InteractID, until they expire. Zero expiry times mean no expiry.
+type GrantRecord struct {
+	ID      string
+	Client  ClientIdentity
+	Request models.GrantRequest
+
+	ContinueToken     string
+	ContinueExpiresAt time.Time
+	InteractID        string
+	InteractExpiresAt time.Time
+	// FinishNonce is the nonce of the AS for the interaction finish
+	// hash, returned as "finish" in the interaction response.
+	FinishNonce string
+	// InteractRef is the interaction reference returned to the client
+	// instance when interaction finishes.
+	InteractRef string
+
+	// Tokens are the values of the access tokens issued for the grant.
+	Tokens    []string
+	CreatedAt time.Time
+}
+
+// NewGrantRecord creates the record of a grant request with newly
+// generated identifier and continuation access token. If the request
+// asks for interaction, the identifier of the interaction urls is
+// generated as well, along with the nonce of the AS if the request asks
+// to be notified when interaction finishes. Expiry times are left for
+// the caller to set.
+func NewGrantRecord(client ClientIdentity, req models.GrantRequest, now time.Time) (GrantRecord, error) {
+	grant := GrantRecord{Client: client, Request: req, CreatedAt: now}
+	var err error
+	grant.ID, err = nonces.Load().Generate()
+	if err != nil {
+		return GrantRecord{}, err
+	}
+	grant.ContinueToken, err = NewTokenValue()
+	if err != nil {
+		return GrantRecord{}, err
+	}
+	if req.Interact == nil {
+		return grant, nil
+	}
+	grant.InteractID, err = nonces.Load().Generate()
+	if err != nil {
+		return GrantRecord{}, err
+	}
+	if req.Interact.Finish != nil {
+		grant.FinishNonce, err = NewNonce()
+		if err != nil {
+			return GrantRecord{}, err
+		}
+	}
+	return grant, nil
+}
+
+// FinishInteraction generates the interaction reference of the grant
+// once the end user has finished interacting, and ends the use of the
+// interaction urls.
+func (g *GrantRecord) FinishInteraction() error {
+	ref, err := NewInteractRef()
+	if err != nil {
+		return err
+	}
+	g.InteractRef = ref
+	g.InteractID = ""
+	g.InteractExpiresAt = time.Time{}
+	return nil
+}
+
+// ContinueExpired reports whether the grant can no longer be continued
+// at the given time.
+func (g GrantRecord) ContinueExpired(now time.Time) bool {
+	return g.ContinueToken == "" || expired(g.ContinueExpiresAt, now)
+}
+
+// InteractExpired reports whether the interaction urls of the grant can
+// no longer be used at the given time.
+func (g GrantRecord) InteractExpired(now time.Time) bool {
+	return g.InteractID == "" || expired(g.InteractExpiresAt, now)
+}
+
+// Expired reports whether the grant can neither be continued nor
+// interacted with at the given time, so that its record can be purged.
+// The access tokens issued for the grant are kept in the [TokenStore]
+// and expire on their own.
+func (g GrantRecord) Expired(now time.Time) bool {
+	return g.ContinueExpired(now) && g.InteractExpired(now)
+}
+
+// expired reports whether the expiry time has passed at now. The zero
+// value never expires.
+func expired(at, now time.Time) bool {
+	return !at.IsZero() && !now.Before(at)
+}
+
+// ExpiresIn converts the expiry time into the number of seconds from
+// now, as in the "expires_in" fields of the responses of the AS. It is
+// zero for the zero time, and at least one second for expiry times that
+// have not passed.
+func ExpiresIn(at, now time.Time) int {
+	if at.IsZero() || !now.Before(at) {
+		return 0
+	}
+	return int(math.Ceil(at.Sub(now).Seconds()))
+}
+
+// ExpiresAt converts the "expires_in" seconds into the expiry time. It is
+// the zero time for zero seconds.
+func ExpiresAt(expiresIn int, now time.Time) time.Time {
+	if expiresIn <= 0 {
+		return time.Time{}
+	}
+	return now.Add(time.Duration(expiresIn) * time.Second)
+}
+
+// GrantStore persists the grants processed by the AS.
+type GrantStore interface {
+	Get(ctx context.Context, id string) (GrantRecord, error)
+	// ByContinueToken looks up the grant by its continuation access token.
+	ByContinueToken(ctx context.Context, token string) (GrantRecord, error)
+	// ByInteraction looks up the grant by the identifier of its
+	// interaction urls.
+	ByInteraction(ctx context.Context, id string) (GrantRecord, error)
+	Put(ctx context.Context, grant GrantRecord) error
+	Delete(ctx context.Context, id string) error
+	// Purge deletes the grants expired at now, and returns how many.
+	Purge(ctx context.Context, now time.Time) (int, error)
+}
+
+// MemoryGrantStore is a concurrency-safe in-memory [GrantStore].
+// The zero value is ready to use.
+type MemoryGrantStore struct {
+	mu         sync.RWMutex
+	grants     map[string]GrantRecord
+	byContinue map[string]string
+	byInteract map[string]string
+}
+
+// Get implements [GrantStore] interface.
+func (s *MemoryGrantStore) Get(ctx context.Context, id string) (GrantRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	grant, ok := s.grants[id]
+	if !ok {
+		return GrantRecord{}, ErrNotFound
+	}
+	return grant, nil
+}
+
+// ByContinueToken implements [GrantStore] interface.
+func (s *MemoryGrantStore) ByContinueToken(ctx context.Context, token string) (GrantRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	grant, ok := s.grants[s.byContinue[token]]
+	if !ok {
+		return GrantRecord{}, ErrNotFound
+	}
+	return grant, nil
+}
+
+// ByInteraction implements [GrantStore] interface.
+func (s *MemoryGrantStore) ByInteraction(ctx context.Context, id string) (GrantRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	grant, ok := s.grants[s.byInteract[id]]
+	if !ok {
+		return GrantRecord{}, ErrNotFound
+	}
+	return grant, nil
+}
+
+// Put implements [GrantStore] interface. The grant replaces any grant of
+// the same identifier, along with its continuation access token and
+// interaction urls.
+func (s *MemoryGrantStore) Put(ctx context.Context, grant GrantRecord) error {
+	if grant.ID == "" {
+		return fmt.Errorf("%w: grant id", models.ErrMissingField)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.grants == nil {
+		s.grants = make(map[string]GrantRecord)
+		s.byContinue = make(map[string]string)
+		s.byInteract = make(map[string]string)
+	}
+	s.remove(grant.ID)
+	s.grants[grant.ID] = grant
+	if grant.ContinueToken != "" {
+		s.byContinue[grant.ContinueToken] = grant.ID
+	}
+	if grant.InteractID != "" {
+		s.byInteract[grant.InteractID] = grant.ID
+	}
+	return nil
+}
+
+// Delete implements [GrantStore] interface.
+func (s *MemoryGrantStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.remove(id)
+	return nil
+}
+
+// Purge implements [GrantStore] interface.
+func (s *MemoryGrantStore) Purge(ctx context.Context, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for id, grant := range s.grants {
+		if grant.Expired(now) {
+			s.remove(id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// remove deletes the grant and its indexes. The caller must hold the
+// write lock.
+func (s *MemoryGrantStore) remove(id string) {
+	old, ok := s.grants[id]
+	if !ok {
+		return
+	}
+	delete(s.byContinue, old.ContinueToken)
+	delete(s.byInteract, old.InteractID)
+	delete(s.grants, id)
+}
+
+// ContinueGrant returns the grant continued with the continuation access
+// token. Unknown and expired tokens are rejected with the gnap error
+// "invalid_continuation".
+func ContinueGrant(ctx context.Context, store GrantStore, token string, now time.Time) (GrantRecord, error) {
+	grant, err := store.ByContinueToken(ctx, token)
+	if errors.Is(err, ErrNotFound) {
+		return GrantRecord{}, fmt.Errorf("%w: %w", models.GNAPError{Code: "invalid_continuation"}, err)
+	}
+	if err != nil {
+		return GrantRecord{}, err
+	}
+	if grant.ContinueExpired(now) {
+		return GrantRecord{}, fmt.Errorf("%w: %w", models.GNAPError{Code: "invalid_continuation"}, ErrExpired)
+	}
+	return grant, nil
+}
+
+// InteractGrant returns the grant of the interaction url visited by the
+// end user. Unknown and expired interactions are rejected with the gnap
+// error "invalid_interaction".
+func InteractGrant(ctx context.Context, store GrantStore, id string, now time.Time) (GrantRecord, error) {
+	grant, err := store.ByInteraction(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return GrantRecord{}, fmt.Errorf("%w: %w", models.GNAPError{Code: "invalid_interaction"}, err)
+	}
+	if err != nil {
+		return GrantRecord{}, err
+	}
+	if grant.InteractExpired(now) {
+		return GrantRecord{}, fmt.Errorf("%w: %w", models.GNAPError{Code: "invalid_interaction"}, ErrExpired)
+	}
+	return grant, nil
+}
+
+// ActiveToken returns the access token if it has not expired, and
+// [ErrExpired] otherwise.
+func ActiveToken(ctx context.Context, store TokenStore, value string, now time.Time) (Token, error) {
+	token, err := store.Get(ctx, value)
+	if err != nil {
+		return Token{}, err
+	}
+	if token.Expired(now) {
+		return Token{}, ErrExpired
+	}
+	return token, nil
+}
@@ -0,0 +1,171 @@
+package as
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bingxueshuang/gnap/models"
+	"github.com/bingxueshuang/gnap/nonce"
+)
+
+// fixedGenerators makes the generated values deterministic for the
+// test: token values are all "H", and the nonces are all "A", then all
+// "B" and so on.
+func fixedGenerators(t *testing.T) {
+	t.Helper()
+	var random []byte
+	for i := byte(0); i < 26; i++ {
+		random = append(random, bytes.Repeat([]byte{i}, nonce.DefaultLength)...)
+	}
+	SetNonceGenerator(nonce.MustNew(nonce.WithReader(bytes.NewReader(random))))
+	SetTokenGenerator(nonce.MustNew(nonce.WithLength(40), nonce.WithReader(bytes.NewReader(bytes.Repeat([]byte{7}, 40)))))
+	t.Cleanup(func() {
+		SetNonceGenerator(nil)
+		SetTokenGenerator(nil)
+	})
+}
+
+func TestNewGrantRecord(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	uri, _ := models.ParseURL("https://client.example.net/return/123455")
+	tests := []struct {
+		name         string
+		interact     *models.IARequest
+		wantInteract string
+		wantNonce    string
+	}{
+		{name: "no interaction"},
+		{name: "interaction", interact: &models.IARequest{Start: []models.IAStart{{Mode: models.ModeCode}}}, wantInteract: strings.Repeat("B", 20)},
+		{
+			name:         "finish",
+			interact:     &models.IARequest{Start: []models.IAStart{{Mode: models.ModeRedirect}}, Finish: &models.IAFinish{Method: models.MethodRedirect, URI: &uri, Nonce: "LKLTI25DK82FX4T4QFZC"}},
+			wantInteract: strings.Repeat("B", 20),
+			wantNonce:    strings.Repeat("C", 20),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixedGenerators(t)
+			got, err := NewGrantRecord(ClientIdentity{InstanceID: "7C7C4AZ9KHRS6X63AJAO"}, models.GrantRequest{Interact: tt.interact}, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.ID != strings.Repeat("A", 20) || got.ContinueToken != strings.Repeat("H", 40) || !got.CreatedAt.Equal(now) {
+				t.Errorf("NewGrantRecord() = %+v", got)
+			}
+			if got.InteractID != tt.wantInteract || got.FinishNonce != tt.wantNonce {
+				t.Errorf("NewGrantRecord() InteractID = %q, FinishNonce = %q, want %q, %q", got.InteractID, got.FinishNonce, tt.wantInteract, tt.wantNonce)
+			}
+		})
+	}
+}
+
+func TestGrantRecord_FinishInteraction(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	fixedGenerators(t)
+	grant := GrantRecord{ID: "grant", InteractID: "4CF492MLVMSW9MKMXKHQ", InteractExpiresAt: now.Add(time.Minute)}
+	if err := grant.FinishInteraction(); err != nil {
+		t.Fatal(err)
+	}
+	if grant.InteractRef != strings.Repeat("A", 20) || !grant.InteractExpired(now) {
+		t.Errorf("GrantRecord.FinishInteraction() = %+v", grant)
+	}
+}
+
+func TestContinueGrant(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := &MemoryGrantStore{}
+	ctx := context.Background()
+	_ = store.Put(ctx, GrantRecord{ID: "active", ContinueToken: "80UPRY5NM33OMUKMKSKU", ContinueExpiresAt: now.Add(time.Minute)})
+	_ = store.Put(ctx, GrantRecord{ID: "expired", ContinueToken: "expired", ContinueExpiresAt: now})
+	_ = store.Put(ctx, GrantRecord{ID: "forever", ContinueToken: "forever"})
+	tests := []struct {
+		name    string
+		token   string
+		want    string
+		wantErr error
+	}{
+		{name: "active", token: "80UPRY5NM33OMUKMKSKU", want: "active"},
+		{name: "no expiry", token: "forever", want: "forever"},
+		{name: "expired", token: "expired", wantErr: ErrExpired},
+		{name: "unknown", token: "unknown", wantErr: ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ContinueGrant(ctx, store, tt.token, now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ContinueGrant() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, models.ErrGInvalidContinuation) {
+				t.Errorf("ContinueGrant() error = %v, want %v", err, models.ErrGInvalidContinuation)
+			}
+			if got.ID != tt.want {
+				t.Errorf("ContinueGrant() = %q, want %q", got.ID, tt.want)
+			}
+		})
+	}
+}
+
+func TestInteractGrant(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := &MemoryGrantStore{}
+	ctx := context.Background()
+	_ = store.Put(ctx, GrantRecord{ID: "a", InteractID: "4CF492MLVMSW9MKM", InteractExpiresAt: now.Add(time.Minute)})
+	_ = store.Put(ctx, GrantRecord{ID: "b", InteractID: "expired", InteractExpiresAt: now.Add(-time.Second)})
+	// replacing the grant drops its previous interaction
+	_ = store.Put(ctx, GrantRecord{ID: "c", InteractID: "old"})
+	_ = store.Put(ctx, GrantRecord{ID: "c", InteractID: "new"})
+	tests := []struct {
+		name    string
+		id      string
+		want    string
+		wantErr error
+	}{
+		{name: "active", id: "4CF492MLVMSW9MKM", want: "a"},
+		{name: "expired", id: "expired", wantErr: ErrExpired},
+		{name: "replaced", id: "old", wantErr: ErrNotFound},
+		{name: "replacement", id: "new", want: "c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := InteractGrant(ctx, store, tt.id, now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("InteractGrant() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, models.ErrGInvalidInteraction) {
+				t.Errorf("InteractGrant() error = %v, want %v", err, models.ErrGInvalidInteraction)
+			}
+			if got.ID != tt.want {
+				t.Errorf("InteractGrant() = %q, want %q", got.ID, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpiresIn(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		name string
+		at   time.Time
+		want int
+	}{
+		{name: "no expiry", want: 0},
+		{name: "seconds", at: now.Add(time.Minute), want: 60},
+		{name: "rounded up", at: now.Add(1500 * time.Millisecond), want: 2},
+		{name: "passed", at: now.Add(-time.Second), want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExpiresIn(tt.at, now); got != tt.want {
+				t.Errorf("ExpiresIn() = %v, want %v", got, tt.want)
+			}
+			if tt.want > 0 && !ExpiresAt(tt.want, now).Before(tt.at.Add(time.Second)) {
+				t.Errorf("ExpiresAt() = %v, want about %v", ExpiresAt(tt.want, now), tt.at)
+			}
+		})
+	}
+}
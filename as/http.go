@@ -0,0 +1,21 @@
+package as
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/bingxueshuang/gnap/models"
+)
+
+// writeJSON writes v as json response body with the given status.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes the gnap error as json response body, with the
+// status code of the error.
+func writeError(w http.ResponseWriter, err models.GNAPError) {
+	err.WriteResponse(w)
+}
@@ -0,0 +1,111 @@
+package as
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/bingxueshuang/gnap/models"
+)
+
+// IntrospectionHandler serves the token introspection endpoint, which
+// resource servers call to learn about opaque access tokens issued by
+// the AS.
+type IntrospectionHandler struct {
+	store        TokenStore
+	authenticate func(r *http.Request, rs models.RSIdentity) error
+	now          func() time.Time
+}
+
+// NewIntrospectionHandler is the constructor for [IntrospectionHandler]
+// with mandatory token store and optional parameters.
+func NewIntrospectionHandler(store TokenStore, options ...introspectionOption) (h *IntrospectionHandler, err error) {
+	h = &IntrospectionHandler{store: store, now: time.Now}
+	for _, setter := range options {
+		err = setter(h)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+// introspectionOption is functional parameter for introspection
+// handler constructor.
+type introspectionOption func(*IntrospectionHandler) error
+
+// WithRSAuthenticator is an optional parameter for
+// [NewIntrospectionHandler] to authenticate the calling RS, usually by
+// verifying the key proof of its request. Without it, any caller is
+// allowed to introspect tokens.
+func WithRSAuthenticator(fn func(r *http.Request, rs models.RSIdentity) error) introspectionOption {
+	return func(h *IntrospectionHandler) error {
+		h.authenticate = fn
+		return nil
+	}
+}
+
+// ServeHTTP implements [http.Handler] interface.
+func (h *IntrospectionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	var req models.IntrospectRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err == nil {
+		err = req.Validate()
+	}
+	if err != nil {
+		writeError(w, models.GNAPError{Code: "invalid_request"})
+		return
+	}
+	if h.authenticate != nil {
+		err = h.authenticate(r, req.ResourceServer)
+		if err != nil {
+			writeError(w, models.GNAPError{Code: "invalid_client"})
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, h.introspect(r, req))
+}
+
+// introspect looks up the token and describes it to the RS.
+func (h *IntrospectionHandler) introspect(r *http.Request, req models.IntrospectRequest) models.IntrospectResponse {
+	inactive := models.IntrospectResponse{Active: false}
+	token, err := h.store.Get(r.Context(), req.AccessToken)
+	if err != nil || token.Expired(h.now()) {
+		return inactive
+	}
+	if !token.Bearer() && !proofMatches(token, req.Proof) {
+		return inactive
+	}
+	if !models.IsSubset(req.Access, token.Access) {
+		return inactive
+	}
+	res := models.IntrospectResponse{
+		Active:     true,
+		Access:     token.Access,
+		Key:        token.Key,
+		Flags:      token.Flags,
+		InstanceID: token.InstanceID,
+	}
+	if !token.IssuedAt.IsZero() {
+		res.IssuedAt = token.IssuedAt.Unix()
+	}
+	if !token.ExpiresAt.IsZero() {
+		res.ExpiresAt = token.ExpiresAt.Unix()
+	}
+	return res
+}
+
+// proofMatches reports whether a key-bound token was presented with the
+// proof method of its key. The proof is required, since the RS must not
+// accept a key-bound token as a bearer token.
+func proofMatches(token Token, proof models.ProofMethod) bool {
+	if proof == "" || token.Key == nil || token.Key.Proof == nil {
+		return false
+	}
+	return token.Key.Proof.Proof() == proof
+}
@@ -0,0 +1,139 @@
+package as
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bingxueshuang/gnap/models"
+)
+
+func TestIntrospectionHandler_ServeHTTP(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	access := []models.AccessRight{{Type: "photo-api", Actions: []string{"read"}}}
+	key := &models.ClientKey{Proof: models.ProofHTTPSig, JWK: json.RawMessage(`{"kty":"OKP"}`)}
+	store := &MemoryTokenStore{}
+	_ = store.Put(context.Background(), Token{
+		Value:     "OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0",
+		Access:    access,
+		Key:       key,
+		IssuedAt:  now.Add(-time.Minute),
+		ExpiresAt: now.Add(time.Hour),
+	})
+	_ = store.Put(context.Background(), Token{
+		Value:  "bearer",
+		Access: access,
+		Flags:  []models.TokenFlag{models.FlagBearer},
+	})
+	_ = store.Put(context.Background(), Token{
+		Value:     "expired",
+		Access:    access,
+		Key:       key,
+		ExpiresAt: now.Add(-time.Second),
+	})
+	h, err := NewIntrospectionHandler(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.now = func() time.Time { return now }
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		status int
+		want   models.IntrospectResponse
+	}{
+		{
+			name:   "active",
+			method: http.MethodPost,
+			body:   `{"access_token":"OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0","proof":"httpsig","resource_server":"7C7C4AZ9KHRS6X63AJAO"}`,
+			status: http.StatusOK,
+			want: models.IntrospectResponse{
+				Active:    true,
+				Access:    access,
+				Key:       key,
+				IssuedAt:  now.Add(-time.Minute).Unix(),
+				ExpiresAt: now.Add(time.Hour).Unix(),
+			},
+		},
+		{
+			name:   "proof mismatch",
+			method: http.MethodPost,
+			body:   `{"access_token":"OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0","proof":"mtls","resource_server":"7C7C4AZ9KHRS6X63AJAO"}`,
+			status: http.StatusOK,
+		},
+		{
+			name:   "bound without proof",
+			method: http.MethodPost,
+			body:   `{"access_token":"OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0","resource_server":"7C7C4AZ9KHRS6X63AJAO"}`,
+			status: http.StatusOK,
+		},
+		{
+			name:   "bearer",
+			method: http.MethodPost,
+			body:   `{"access_token":"bearer","resource_server":"7C7C4AZ9KHRS6X63AJAO"}`,
+			status: http.StatusOK,
+			want: models.IntrospectResponse{
+				Active: true,
+				Access: access,
+				Flags:  []models.TokenFlag{models.FlagBearer},
+			},
+		},
+		{
+			name:   "access not covered",
+			method: http.MethodPost,
+			body:   `{"access_token":"OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0","proof":"httpsig","resource_server":"7C7C4AZ9KHRS6X63AJAO","access":[{"type":"photo-api","actions":["write"]}]}`,
+			status: http.StatusOK,
+		},
+		{
+			name:   "expired",
+			method: http.MethodPost,
+			body:   `{"access_token":"expired","resource_server":"7C7C4AZ9KHRS6X63AJAO"}`,
+			status: http.StatusOK,
+		},
+		{
+			name:   "unknown",
+			method: http.MethodPost,
+			body:   `{"access_token":"unknown","resource_server":"7C7C4AZ9KHRS6X63AJAO"}`,
+			status: http.StatusOK,
+		},
+		{
+			name:   "malformed",
+			method: http.MethodPost,
+			body:   `{"resource_server":"7C7C4AZ9KHRS6X63AJAO"}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "method",
+			method: http.MethodGet,
+			status: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/introspect", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("IntrospectionHandler.ServeHTTP() status = %v, want %v", rec.Code, tt.status)
+				return
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+			var got models.IntrospectResponse
+			err := json.NewDecoder(rec.Body).Decode(&got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("IntrospectionHandler.ServeHTTP() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,302 @@
+package as
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bingxueshuang/gnap/models"
+	"github.com/bingxueshuang/gnap/proof"
+)
+
+// LimitStore persists the state of a [Limiter], so that it can be shared
+// between instances of the AS.
+type LimitStore interface {
+	// NotBefore returns the earliest time the grant of the continuation
+	// access token may be continued, or the zero value if unrestricted.
+	NotBefore(ctx context.Context, token string) (time.Time, error)
+	// SetNotBefore sets the earliest time the grant of the continuation
+	// access token may be continued.
+	SetNotBefore(ctx context.Context, token string, t time.Time) error
+	// Attempt records an attempt of key made at the given time, and
+	// returns the number of attempts of key made after since, including
+	// this one. Recording and counting must be atomic.
+	Attempt(ctx context.Context, key string, at, since time.Time) (int, error)
+	ResetAttempts(ctx context.Context, key string) error
+}
+
+// MemoryLimitStore is a concurrency-safe in-memory [LimitStore].
+// The zero value is ready to use. Entries that no longer restrict
+// anything are dropped by Purge, which should be run periodically, for
+// example by a [Sweeper] with [WithLimitPurger].
+type MemoryLimitStore struct {
+	mu        sync.Mutex
+	notBefore map[string]time.Time
+	attempts  map[string]attempts
+}
+
+// attempts are the recent attempts of a key.
+type attempts struct {
+	times []time.Time
+	// expiresAt is when the last attempt leaves the window.
+	expiresAt time.Time
+}
+
+// NotBefore implements [LimitStore] interface.
+func (s *MemoryLimitStore) NotBefore(ctx context.Context, token string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.notBefore[token], nil
+}
+
+// SetNotBefore implements [LimitStore] interface.
+func (s *MemoryLimitStore) SetNotBefore(ctx context.Context, token string, t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.notBefore == nil {
+		s.notBefore = make(map[string]time.Time)
+	}
+	s.notBefore[token] = t
+	return nil
+}
+
+// Attempt implements [LimitStore] interface. Attempts made until since
+// are dropped.
+func (s *MemoryLimitStore) Attempt(ctx context.Context, key string, at, since time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.attempts == nil {
+		s.attempts = make(map[string]attempts)
+	}
+	times := s.attempts[key].times
+	i := 0
+	for i < len(times) && !times[i].After(since) {
+		i++
+	}
+	times = append(times[i:], at)
+	s.attempts[key] = attempts{times: times, expiresAt: at.Add(at.Sub(since))}
+	return len(times), nil
+}
+
+// ResetAttempts implements [LimitStore] interface.
+func (s *MemoryLimitStore) ResetAttempts(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.attempts, key)
+	return nil
+}
+
+// Purge deletes the continuation waits passed at now, and the attempts
+// of the keys whose attempts have all left the window, and returns how
+// many entries were deleted. It implements [Purger] interface.
+func (s *MemoryLimitStore) Purge(ctx context.Context, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for token, nb := range s.notBefore {
+		if !now.Before(nb) {
+			delete(s.notBefore, token)
+			n++
+		}
+	}
+	for key, a := range s.attempts {
+		if !now.Before(a.expiresAt) {
+			delete(s.attempts, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// Limiter provides middleware that enforces the pace of continuation
+// requests with the gnap error "too_fast", and limits the attempts
+// without success, such as entering user codes, with
+// "too_many_attempts".
+type Limiter struct {
+	store       LimitStore
+	maxAttempts int
+	window      time.Duration
+	now         func() time.Time
+}
+
+// NewLimiter is the constructor for [Limiter] with mandatory store and
+// optional parameters. By default, 5 attempts without success are
+// allowed within 15 minutes.
+func NewLimiter(store LimitStore, options ...limiterOption) (l *Limiter, err error) {
+	l = &Limiter{
+		store:       store,
+		maxAttempts: 5,
+		window:      15 * time.Minute,
+		now:         time.Now,
+	}
+	for _, setter := range options {
+		err = setter(l)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// limiterOption is functional parameter for limiter constructor.
+type limiterOption func(*Limiter) error
+
+// WithMaxAttempts is an optional parameter for [NewLimiter] to set how
+// many attempts without success are allowed within the window.
+func WithMaxAttempts(n int) limiterOption {
+	return func(l *Limiter) error {
+		if n <= 0 {
+			return fmt.Errorf("invalid max attempts: %d", n)
+		}
+		l.maxAttempts = n
+		return nil
+	}
+}
+
+// WithClock is an optional parameter for [NewLimiter] to set the clock
+// that the limiter reads the current time from, as in tests.
+func WithClock(now func() time.Time) limiterOption {
+	return func(l *Limiter) error {
+		if now == nil {
+			return errors.New("missing clock")
+		}
+		l.now = now
+		return nil
+	}
+}
+
+// WithAttemptWindow is an optional parameter for [NewLimiter] to set the
+// window over which attempts are counted.
+func WithAttemptWindow(window time.Duration) limiterOption {
+	return func(l *Limiter) error {
+		if window <= 0 {
+			return fmt.Errorf("invalid attempt window: %v", window)
+		}
+		l.window = window
+		return nil
+	}
+}
+
+// Pace is middleware for the grant and continuation endpoints. Grant
+// responses with a continuation wait are recorded against the issued
+// continuation access token, and continuation requests presenting that
+// token earlier are rejected with "too_fast".
+func (l *Limiter) Pace(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if token := proof.AccessToken(r); token != "" {
+			nb, err := l.store.NotBefore(ctx, token)
+			if err != nil {
+				writeError(w, models.GNAPError{Code: "request_denied"})
+				return
+			}
+			if now := l.now(); now.Before(nb) {
+				wait := math.Ceil(nb.Sub(now).Seconds())
+				w.Header().Set("Retry-After", strconv.Itoa(int(wait)))
+				writeError(w, models.GNAPError{Code: "too_fast"})
+				return
+			}
+		}
+		cw := &captureWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(cw, r)
+		if cw.status == http.StatusOK {
+			var res models.GrantResponse
+			err := json.Unmarshal(cw.buf.Bytes(), &res)
+			if err == nil && res.Continue != nil && res.Continue.Wait > 0 {
+				now := l.now()
+				nb := now.Add(time.Duration(res.Continue.Wait) * time.Second)
+				_ = l.store.SetNotBefore(ctx, res.Continue.Token.Value, nb)
+			}
+		}
+		cw.flush()
+	})
+}
+
+// LimitAttempts is middleware for endpoints where the end user or client
+// instance may guess, such as the user code entry and interaction
+// endpoints. Every request submitting a guess counts as an attempt of
+// the key of the request, and once more attempts than allowed were made
+// within the window, further guesses are rejected with
+// "too_many_attempts". Requests of the safe methods GET and HEAD, such
+// as rendering the entry form, are passed through and not counted. The
+// count is reset only when the handler reports success with
+// [AttemptSucceeded]. If key is nil, requests are keyed by the address
+// of the remote host.
+func (l *Limiter) LimitAttempts(key func(r *http.Request) string, next http.Handler) http.Handler {
+	if key == nil {
+		key = remoteHost
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx := r.Context()
+		k := key(r)
+		now := l.now()
+		n, err := l.store.Attempt(ctx, k, now, now.Add(-l.window))
+		if err != nil || n > l.maxAttempts {
+			writeError(w, models.GNAPError{Code: "too_many_attempts"})
+			return
+		}
+		succeeded := new(atomic.Bool)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, attemptKey{}, succeeded)))
+		if succeeded.Load() {
+			_ = l.store.ResetAttempts(ctx, k)
+		}
+	})
+}
+
+// attemptKey is the context key of the outcome of an attempt.
+type attemptKey struct{}
+
+// AttemptSucceeded reports to [Limiter.LimitAttempts] that the attempt
+// of the request with the context succeeded, such as a user code that
+// was entered correctly, so that the count of attempts is reset.
+func AttemptSucceeded(ctx context.Context) {
+	if succeeded, ok := ctx.Value(attemptKey{}).(*atomic.Bool); ok {
+		succeeded.Store(true)
+	}
+}
+
+// remoteHost returns the address of the remote host of the request.
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// captureWriter holds back the response so that it can be inspected
+// before it is sent.
+type captureWriter struct {
+	http.ResponseWriter
+	status int
+	buf    bytes.Buffer
+}
+
+// WriteHeader implements [http.ResponseWriter] interface.
+func (w *captureWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+// Write implements [http.ResponseWriter] interface.
+func (w *captureWriter) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+// flush sends the response held back.
+func (w *captureWriter) flush() {
+	w.ResponseWriter.WriteHeader(w.status)
+	_, _ = w.ResponseWriter.Write(w.buf.Bytes())
+}
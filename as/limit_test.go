@@ -0,0 +1,193 @@
+package as
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLimiter_Pace(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	l, err := NewLimiter(&MemoryLimitStore{}, WithClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := l.Pace(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"continue": map[string]any{
+				"access_token": map[string]any{"value": r.URL.Query().Get("issue")},
+				"uri":          "https://server.example.com/continue",
+				"wait":         30,
+			},
+		})
+	}))
+	serve := func(token, issue string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/continue?issue="+issue, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "GNAP "+token)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		token   string
+		issue   string
+		status  int
+		want    string
+		retry   string
+	}{
+		{name: "grant", issue: "80UPRY5NM33OMUKMKSKU", status: http.StatusOK, want: "80UPRY5NM33OMUKMKSKU"},
+		{name: "other grant", elapsed: time.Second, issue: "G7YQT4KQQ5TZY9SLSS5E", status: http.StatusOK, want: "G7YQT4KQQ5TZY9SLSS5E"},
+		{name: "too fast", elapsed: 9 * time.Second, token: "80UPRY5NM33OMUKMKSKU", status: http.StatusTooManyRequests, want: "too_fast", retry: "20"},
+		{name: "other too fast", token: "G7YQT4KQQ5TZY9SLSS5E", status: http.StatusTooManyRequests, want: "too_fast", retry: "21"},
+		{name: "after wait", elapsed: 20 * time.Second, token: "80UPRY5NM33OMUKMKSKU", issue: "MNKNYQ8SC3PA3UJKWRJL", status: http.StatusOK, want: "MNKNYQ8SC3PA3UJKWRJL"},
+		{name: "unknown token", elapsed: 30 * time.Second, token: "unknown", issue: "X5B8QGZ2VUE3U3ZRK3AQ", status: http.StatusOK, want: "X5B8QGZ2VUE3U3ZRK3AQ"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = now.Add(tt.elapsed)
+			rec := serve(tt.token, tt.issue)
+			if rec.Code != tt.status || !strings.Contains(rec.Body.String(), tt.want) {
+				t.Errorf("Limiter.Pace() = %d %s, want %d with %q", rec.Code, rec.Body, tt.status, tt.want)
+			}
+			if got := rec.Header().Get("Retry-After"); got != tt.retry {
+				t.Errorf("Limiter.Pace() Retry-After = %q, want %q", got, tt.retry)
+			}
+		})
+	}
+}
+
+// userCodeHandler accepts the user code "A1BC3DFF", and shows the entry
+// form on GET.
+var userCodeHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		return
+	}
+	if r.FormValue("user_code") != "A1BC3DFF" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	AttemptSucceeded(r.Context())
+})
+
+func TestLimiter_LimitAttempts(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	l, err := NewLimiter(&MemoryLimitStore{}, WithMaxAttempts(2), WithAttemptWindow(time.Minute), WithClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := l.LimitAttempts(nil, userCodeHandler)
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		method  string
+		code    string
+		remote  string
+		status  int
+	}{
+		{name: "wrong code", code: "AAAAAAAA", remote: "192.0.2.1:1234", status: http.StatusBadRequest},
+		{name: "right code resets", code: "A1BC3DFF", remote: "192.0.2.1:1234", status: http.StatusOK},
+		{name: "first failure", code: "AAAAAAAA", remote: "192.0.2.1:1234", status: http.StatusBadRequest},
+		{name: "page not counted", method: http.MethodGet, remote: "192.0.2.1:1235", status: http.StatusOK},
+		{name: "second failure", code: "AAAAAAAA", remote: "192.0.2.1:1235", status: http.StatusBadRequest},
+		{name: "limit reached", code: "A1BC3DFF", remote: "192.0.2.1:1236", status: http.StatusTooManyRequests},
+		{name: "page after limit", method: http.MethodGet, remote: "192.0.2.1:1236", status: http.StatusOK},
+		{name: "other host", code: "A1BC3DFF", remote: "192.0.2.2:1234", status: http.StatusOK},
+		{name: "window passed", elapsed: time.Minute, code: "A1BC3DFF", remote: "192.0.2.1:1234", status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = now.Add(tt.elapsed)
+			method := tt.method
+			if method == "" {
+				method = http.MethodPost
+			}
+			req := httptest.NewRequest(method, "/device?user_code="+tt.code, nil)
+			req.RemoteAddr = tt.remote
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("Limiter.LimitAttempts() status = %d, want %d", rec.Code, tt.status)
+			}
+		})
+	}
+}
+
+func TestLimiter_LimitAttempts_concurrent(t *testing.T) {
+	l, err := NewLimiter(&MemoryLimitStore{}, WithMaxAttempts(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var guesses atomic.Int32
+	h := l.LimitAttempts(nil, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		guesses.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodPost, "/device?user_code=AAAAAAAA", nil)
+			h.ServeHTTP(httptest.NewRecorder(), req)
+		}()
+	}
+	wg.Wait()
+	if got := guesses.Load(); got != 2 {
+		t.Errorf("guesses passed to handler = %d, want 2", got)
+	}
+}
+
+func TestMemoryLimitStore_Purge(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	ctx := context.Background()
+	s := &MemoryLimitStore{}
+	_ = s.SetNotBefore(ctx, "passed", now)
+	_ = s.SetNotBefore(ctx, "waiting", now.Add(time.Second))
+	_, _ = s.Attempt(ctx, "192.0.2.1", now.Add(-time.Minute), now.Add(-2*time.Minute))
+	_, _ = s.Attempt(ctx, "192.0.2.2", now.Add(-time.Second), now.Add(-time.Minute-time.Second))
+	n, err := s.Purge(ctx, now)
+	if err != nil || n != 2 {
+		t.Errorf("MemoryLimitStore.Purge() = %v, %v, want 2", n, err)
+	}
+	if nb, _ := s.NotBefore(ctx, "waiting"); nb.IsZero() {
+		t.Error("MemoryLimitStore.Purge() dropped a pending wait")
+	}
+	if got, _ := s.Attempt(ctx, "192.0.2.2", now, now.Add(-time.Minute)); got != 2 {
+		t.Errorf("MemoryLimitStore.Attempt() = %v after Purge, want 2", got)
+	}
+	if len(s.attempts) != 1 || len(s.notBefore) != 1 {
+		t.Errorf("MemoryLimitStore holds %d attempt keys and %d waits, want 1 and 1", len(s.attempts), len(s.notBefore))
+	}
+}
+
+func TestNewLimiter(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []limiterOption
+		wantErr bool
+	}{
+		{name: "default"},
+		{name: "max attempts", options: []limiterOption{WithMaxAttempts(3)}},
+		{name: "zero max attempts", options: []limiterOption{WithMaxAttempts(0)}, wantErr: true},
+		{name: "negative window", options: []limiterOption{WithAttemptWindow(-time.Second)}, wantErr: true},
+		{name: "clock", options: []limiterOption{WithClock(time.Now)}},
+		{name: "missing clock", options: []limiterOption{WithClock(nil)}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewLimiter(&MemoryLimitStore{}, tt.options...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewLimiter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
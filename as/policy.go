@@ -0,0 +1,103 @@
+package as
+
+import (
+	"context"
+
+	"github.com/bingxueshuang/gnap/models"
+	"github.com/bingxueshuang/gnap/subject"
+)
+
+// ClientIdentity is the identity of the client instance making a grant
+// request, as established by the AS after verifying the key proof.
+type ClientIdentity struct {
+	InstanceID string // instance identifier, if the client is known by one
+	ClassID    string
+	Key        models.ClientKey
+}
+
+// Owner is the identity of the resource owner, as established by the AS
+// through interaction or otherwise.
+type Owner struct {
+	ID subject.ID
+}
+
+// Verdict is the outcome of a policy decision.
+type Verdict int
+
+// Verdicts of a policy decision.
+const (
+	// Grant issues the access in Decision.Access, which may be less
+	// than requested.
+	Grant Verdict = iota
+	// Deny rejects the request with Decision.Error.
+	Deny
+	// Interact requires the RO to interact with the AS before the
+	// request is decided again.
+	Interact
+)
+
+// String implements the [fmt.Stringer] interface.
+func (v Verdict) String() string {
+	switch v {
+	case Grant:
+		return "grant"
+	case Deny:
+		return "deny"
+	case Interact:
+		return "interact"
+	}
+	return "unknown"
+}
+
+// Decision is the decision of a [Policy] on a grant request.
+type Decision struct {
+	Verdict Verdict
+	// Access holds the access to grant per requested token, keyed by
+	// label. The token of a single access token request has the empty
+	// label. Requested tokens missing from Access are not issued.
+	Access map[string][]models.AccessRight
+	// Subject reports whether the requested subject information may be
+	// released.
+	Subject bool
+	// Error is the reason of denial.
+	Error *models.GNAPError
+}
+
+// Downscoped reports whether the decision grants less access than the
+// request asks for.
+func (d Decision) Downscoped(req models.GrantRequest) bool {
+	if req.AccessToken == nil {
+		return false
+	}
+	tokens := req.AccessToken.Multiple
+	if tokens == nil {
+		tokens = []models.TokenRequest{req.AccessToken.Single}
+	}
+	for _, token := range tokens {
+		granted, ok := d.Access[token.Label]
+		if !ok || !models.IsSubset(token.Access, granted) {
+			return true
+		}
+	}
+	return false
+}
+
+// denial is the decision denying the request with the error code.
+func denial(code string) Decision {
+	return Decision{Verdict: Deny, Error: &models.GNAPError{Code: code}}
+}
+
+// Policy decides which access to grant for a grant request, and whether
+// interaction with the RO is required. The owner is nil when the RO has
+// not been identified yet, typically before interaction.
+type Policy interface {
+	Decide(ctx context.Context, req models.GrantRequest, client ClientIdentity, owner *Owner) (Decision, error)
+}
+
+// PolicyFunc is an adapter to use ordinary functions as [Policy].
+type PolicyFunc func(ctx context.Context, req models.GrantRequest, client ClientIdentity, owner *Owner) (Decision, error)
+
+// Decide implements [Policy] interface.
+func (f PolicyFunc) Decide(ctx context.Context, req models.GrantRequest, client ClientIdentity, owner *Owner) (Decision, error) {
+	return f(ctx, req, client, owner)
+}
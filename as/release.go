@@ -0,0 +1,149 @@
+package as
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bingxueshuang/gnap/models"
+	"github.com/bingxueshuang/gnap/subject"
+	"golang.org/x/exp/slices"
+)
+
+// OwnerRecord is the identity record of an RO, holding the values from
+// which the AS builds the subject identifiers it releases. Empty values
+// are not released.
+type OwnerRecord struct {
+	// ID identifies the RO in consent records.
+	ID subject.ID
+
+	Account string // "acct" uri
+	Email   string
+	Issuer  string
+	Subject string // subject at Issuer
+	Opaque  string
+	Phone   string
+	DID     string
+	URI     string
+	// UpdatedAt is when the identity record last changed.
+	UpdatedAt time.Time
+}
+
+// id builds the subject identifier of the format, or returns false if
+// the record has no value for it.
+func (rec OwnerRecord) id(format subject.Format) (subject.ID, bool, error) {
+	var id subject.ID
+	var err error
+	switch {
+	case format == subject.Account && rec.Account != "":
+		id, err = subject.NewIDAccount(rec.Account)
+	case format == subject.Email && rec.Email != "":
+		id, err = subject.NewIDEmail(rec.Email)
+	case format == subject.IssuerSubject && rec.Issuer != "" && rec.Subject != "":
+		id, err = subject.NewIDIssSub(rec.Issuer, rec.Subject)
+	case format == subject.Opaque && rec.Opaque != "":
+		id, err = subject.NewIDOpaque(rec.Opaque)
+	case format == subject.PhoneNumber && rec.Phone != "":
+		id, err = subject.NewIDPhone(rec.Phone)
+	case format == subject.DID && rec.DID != "":
+		id, err = subject.NewIDdid(rec.DID)
+	case format == subject.URI && rec.URI != "":
+		id, err = subject.NewIDuri(rec.URI)
+	default:
+		return subject.ID{}, false, nil
+	}
+	if err != nil {
+		return subject.ID{}, false, fmt.Errorf("subject format %s: %w", format, err)
+	}
+	return id, true, nil
+}
+
+// SubjectRelease builds the subject information returned to the client
+// instance about the RO. Assertions are not built; the AS adds them to
+// the response on its own.
+type SubjectRelease struct {
+	supported []subject.Format
+	now       func() time.Time
+}
+
+// NewSubjectRelease is the constructor for [SubjectRelease] with the
+// mandatory discovery document of the AS, whose supported subject
+// identifier formats limit the formats released.
+func NewSubjectRelease(d models.Discovery) (*SubjectRelease, error) {
+	for _, format := range d.SubFormats {
+		if !format.Valid() {
+			return nil, fmt.Errorf("%w: %s", subject.ErrInvalidFormat, format)
+		}
+	}
+	return &SubjectRelease{supported: slices.Clone(d.SubFormats), now: time.Now}, nil
+}
+
+// Release returns the subject information requested about the RO, with
+// a subject identifier for every format that the client instance
+// requested, the AS supports and the identity record has a value for,
+// in the order requested. An identifier of format [subject.Aliases]
+// lists the identifiers of the other formats both requested and
+// supported, so it never releases more than those formats would. The
+// consent must be the current consent of the RO and approve the release
+// of subject information, else the error is the gnap error
+// "user_denied". It returns nil if there is nothing to release.
+func (r *SubjectRelease) Release(req *models.SubRequest, rec OwnerRecord, consent Consent) (*models.SubResponse, error) {
+	if req == nil {
+		return nil, nil
+	}
+	if !consent.Subject || consent.Expired(r.now()) || !subject.Equal(consent.Owner, rec.ID) {
+		return nil, models.GNAPError{Code: "user_denied", Desc: "release of subject information not approved"}
+	}
+	var ids []subject.ID
+	var released []subject.Format
+	for _, format := range req.SFormats {
+		if slices.Contains(released, format) || !slices.Contains(r.supported, format) {
+			continue
+		}
+		id, ok, err := r.build(format, req.SFormats, rec)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			ids = append(ids, id)
+			released = append(released, format)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	res := &models.SubResponse{SubIDs: ids}
+	if !rec.UpdatedAt.IsZero() {
+		updated := rec.UpdatedAt
+		res.UpdatedAt = &updated
+	}
+	return res, nil
+}
+
+// build builds the subject identifier of the format, collecting the
+// identifiers of the other requested and supported formats for aliases.
+func (r *SubjectRelease) build(format subject.Format, requested []subject.Format, rec OwnerRecord) (subject.ID, bool, error) {
+	if format != subject.Aliases {
+		return rec.id(format)
+	}
+	var aliases []subject.NoAlias
+	for _, f := range r.supported {
+		if f == subject.Aliases || !slices.Contains(requested, f) {
+			continue
+		}
+		id, ok, err := rec.id(f)
+		if err != nil {
+			return subject.ID{}, false, err
+		}
+		if ok {
+			aliases = append(aliases, id.NoAlias())
+		}
+	}
+	if len(aliases) == 0 {
+		return subject.ID{}, false, nil
+	}
+	id, err := subject.NewIDAliases(aliases)
+	if err != nil {
+		return subject.ID{}, false, fmt.Errorf("subject format %s: %w", format, err)
+	}
+	return id, true, nil
+}
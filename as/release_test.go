@@ -0,0 +1,100 @@
+package as
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bingxueshuang/gnap/models"
+	"github.com/bingxueshuang/gnap/subject"
+)
+
+func TestSubjectRelease_Release(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	owner, _ := subject.NewIDOpaque("J2G8G8O4AZ")
+	rec := OwnerRecord{
+		ID:        owner,
+		Email:     "user@example.com",
+		Issuer:    "https://server.example.com/",
+		Subject:   "J2G8G8O4AZ",
+		Opaque:    "J2G8G8O4AZ",
+		UpdatedAt: now.Add(-time.Hour),
+	}
+	consent := Consent{Owner: owner, Client: "instance:7C7C4AZ9KHRS6X63AJAO", Subject: true}
+	r, err := NewSubjectRelease(models.Discovery{
+		SubFormats: []subject.Format{subject.Opaque, subject.IssuerSubject, subject.Email, subject.Aliases},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.now = func() time.Time { return now }
+	request := func(formats ...subject.Format) *models.SubRequest {
+		return &models.SubRequest{SFormats: formats}
+	}
+	tests := []struct {
+		name     string
+		req      *models.SubRequest
+		consent  Consent
+		want     []subject.Format
+		wantCode string
+	}{
+		{name: "no request", consent: consent},
+		{name: "requested order", req: request(subject.Email, subject.Opaque), consent: consent, want: []subject.Format{subject.Email, subject.Opaque}},
+		{name: "unsupported", req: request(subject.PhoneNumber, subject.IssuerSubject), consent: consent, want: []subject.Format{subject.IssuerSubject}},
+		{name: "duplicate", req: request(subject.Opaque, subject.Opaque), consent: consent, want: []subject.Format{subject.Opaque}},
+		{name: "aliases", req: request(subject.Aliases, subject.Email), consent: consent, want: []subject.Format{subject.Aliases, subject.Email}},
+		{name: "aliases alone", req: request(subject.Aliases), consent: consent},
+		{name: "nothing to release", req: request(subject.DID), consent: consent},
+		{name: "not approved", req: request(subject.Opaque), consent: Consent{Owner: owner}, wantCode: "user_denied"},
+		{name: "expired consent", req: request(subject.Opaque), consent: Consent{Owner: owner, Subject: true, ExpiresAt: now}, wantCode: "user_denied"},
+		{name: "other owner", req: request(subject.Opaque), consent: Consent{Owner: subject.ID{Format: subject.Opaque, ID: "other"}, Subject: true}, wantCode: "user_denied"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.Release(tt.req, rec, tt.consent)
+			var gerr models.GNAPError
+			errors.As(err, &gerr)
+			if gerr.Code != tt.wantCode {
+				t.Fatalf("SubjectRelease.Release() error = %v, want code %q", err, tt.wantCode)
+			}
+			if len(tt.want) == 0 {
+				if got != nil {
+					t.Errorf("SubjectRelease.Release() = %+v, want nil", got)
+				}
+				return
+			}
+			if got == nil || len(got.SubIDs) != len(tt.want) {
+				t.Fatalf("SubjectRelease.Release() = %+v, want formats %v", got, tt.want)
+			}
+			for i, id := range got.SubIDs {
+				if id.Format != tt.want[i] || id.Validate() != nil {
+					t.Errorf("SubjectRelease.Release() SubIDs[%d] = %+v, want format %s", i, id, tt.want[i])
+				}
+			}
+			if got.UpdatedAt == nil || !got.UpdatedAt.Equal(rec.UpdatedAt) {
+				t.Errorf("SubjectRelease.Release() UpdatedAt = %v, want %v", got.UpdatedAt, rec.UpdatedAt)
+			}
+		})
+	}
+}
+
+func TestSubjectRelease_aliases(t *testing.T) {
+	r, _ := NewSubjectRelease(models.Discovery{SubFormats: []subject.Format{subject.Email, subject.Opaque, subject.Aliases}})
+	owner, _ := subject.NewIDOpaque("J2G8G8O4AZ")
+	rec := OwnerRecord{ID: owner, Email: "user@example.com", Opaque: "J2G8G8O4AZ", Phone: "+12065550100", Account: "acct:user@example.com"}
+	formats := []subject.Format{subject.Aliases, subject.Opaque, subject.Email, subject.PhoneNumber}
+	got, err := r.Release(&models.SubRequest{SFormats: formats}, rec, Consent{Owner: owner, Subject: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := subject.NewIDAliases([]subject.NoAlias{
+		{Format: subject.Email, Email: "user@example.com"},
+		{Format: subject.Opaque, ID: "J2G8G8O4AZ"},
+	})
+	if len(got.SubIDs) != 3 || !subject.Equal(got.SubIDs[0], want) {
+		t.Errorf("SubjectRelease.Release() = %+v, want %+v", got.SubIDs, want)
+	}
+	if got.UpdatedAt != nil {
+		t.Errorf("SubjectRelease.Release() UpdatedAt = %v, want nil", got.UpdatedAt)
+	}
+}
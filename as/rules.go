@@ -0,0 +1,235 @@
+package as
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bingxueshuang/gnap/models"
+	"github.com/bingxueshuang/gnap/subject"
+	"golang.org/x/exp/slices"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalidRule is returned when a policy rule is malformed.
+var ErrInvalidRule = errors.New("invalid policy rule")
+
+// Rule allows access to the client instances and resource owners it
+// matches. A rule without Clients matches every client instance, and a
+// rule without Owners matches every RO.
+type Rule struct {
+	Name string `json:"name,omitempty"`
+	// Clients lists the instance identifiers or class identifiers of
+	// the client instances the rule applies to.
+	Clients []string `json:"clients,omitempty"`
+	// Owners lists the resource owners the rule applies to. The RO must
+	// be identified for the rule to apply, which requires interaction.
+	Owners []subject.ID `json:"owners,omitempty"`
+	// Access is the most access that the rule allows.
+	Access []models.AccessRight `json:"access,omitempty"`
+	// Subject allows the release of subject information of the RO.
+	Subject bool `json:"subject,omitempty"`
+	// Interact requires interaction with the RO before the rule applies.
+	Interact bool `json:"interact,omitempty"`
+}
+
+// matchesClient reports whether the rule applies to the client instance.
+func (r Rule) matchesClient(client ClientIdentity) bool {
+	if len(r.Clients) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(r.Clients, func(id string) bool {
+		return id != "" && (id == client.InstanceID || id == client.ClassID)
+	})
+}
+
+// matchesOwner reports whether the rule applies to the identified RO.
+func (r Rule) matchesOwner(owner Owner) bool {
+	if len(r.Owners) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(r.Owners, func(id subject.ID) bool {
+		return subject.Equal(id, owner.ID)
+	})
+}
+
+// RulePolicy is a declarative [Policy] configured by a list of rules.
+// The access granted for each requested token is the requested access
+// intersected with the access allowed by every rule that applies. When
+// more access would be allowed after interaction and the client instance
+// can interact, interaction is required first. Requests granted nothing
+// are denied with "request_denied".
+type RulePolicy struct {
+	rules    []Rule
+	registry *models.AccessRegistry
+}
+
+// NewRulePolicy is the constructor for [RulePolicy] with mandatory rules
+// and optional parameters.
+func NewRulePolicy(rules []Rule, options ...rulePolicyOption) (p *RulePolicy, err error) {
+	p = &RulePolicy{}
+	for _, setter := range options {
+		err = setter(p)
+		if err != nil {
+			return nil, err
+		}
+	}
+	p.rules = make([]Rule, len(rules))
+	for i, rule := range rules {
+		if len(rule.Access) == 0 && !rule.Subject {
+			return nil, fmt.Errorf("%w: rule %d allows nothing", ErrInvalidRule, i)
+		}
+		if p.registry != nil {
+			rule.Access, err = p.registry.Expand(rule.Access)
+			if err != nil {
+				return nil, fmt.Errorf("%w: rule %d: %w", ErrInvalidRule, i, err)
+			}
+		}
+		p.rules[i] = rule
+	}
+	return p, nil
+}
+
+// rulePolicyOption is functional parameter for rule policy constructor.
+type rulePolicyOption func(*RulePolicy) error
+
+// WithAccessRegistry is an optional parameter for [NewRulePolicy] to
+// expand access rights by reference, both in the rules and in the grant
+// requests, before comparing them.
+func WithAccessRegistry(registry *models.AccessRegistry) rulePolicyOption {
+	return func(p *RulePolicy) error {
+		p.registry = registry
+		return nil
+	}
+}
+
+// ruleFile is the layout of the rules file.
+type ruleFile struct {
+	Rules []Rule `json:"rules"`
+}
+
+// ParseRules creates a [RulePolicy] from a json document of the form
+// {"rules": [...]}.
+func ParseRules(data []byte, options ...rulePolicyOption) (*RulePolicy, error) {
+	var file ruleFile
+	err := json.Unmarshal(data, &file)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRule, err)
+	}
+	return NewRulePolicy(file.Rules, options...)
+}
+
+// LoadRules creates a [RulePolicy] from the rules file at path. Files
+// with the extension ".yaml" or ".yml" are read as yaml, using the same
+// member names as json; others are read as json.
+func LoadRules(path string, options ...rulePolicyOption) (*RulePolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		data, err = yamlToJSON(data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidRule, err)
+		}
+	}
+	return ParseRules(data, options...)
+}
+
+// yamlToJSON converts the yaml document to json, so that the json
+// decoding of the models applies.
+func yamlToJSON(data []byte) ([]byte, error) {
+	var doc any
+	err := yaml.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+// Decide implements [Policy] interface.
+func (p *RulePolicy) Decide(ctx context.Context, req models.GrantRequest, client ClientIdentity, owner *Owner) (Decision, error) {
+	if p.registry != nil && req.AccessToken != nil {
+		at := *req.AccessToken
+		at.Multiple = slices.Clone(at.Multiple)
+		req.AccessToken = &at
+		err := p.registry.ExpandGrant(&req)
+		if errors.Is(err, models.ErrUnknownReference) {
+			return denial("invalid_request"), nil
+		}
+		if err != nil {
+			return Decision{}, err
+		}
+	}
+	if owner != nil && !userMatches(req.User, *owner) {
+		return denial("unknown_user"), nil
+	}
+	var allowed, pending []models.AccessRight
+	var subjectAllowed, subjectPending bool
+	for _, rule := range p.rules {
+		if !rule.matchesClient(client) {
+			continue
+		}
+		switch {
+		case owner == nil && (rule.Interact || len(rule.Owners) > 0):
+			pending = append(pending, rule.Access...)
+			subjectPending = subjectPending || rule.Subject
+		case owner != nil && !rule.matchesOwner(*owner):
+			continue
+		default:
+			allowed = append(allowed, rule.Access...)
+			// subject information is about the RO, who must be identified
+			subjectAllowed = subjectAllowed || (rule.Subject && owner != nil)
+			subjectPending = subjectPending || (rule.Subject && owner == nil)
+		}
+	}
+	d := Decision{Verdict: Grant, Access: make(map[string][]models.AccessRight)}
+	interact := false
+	for _, token := range requestedTokens(req) {
+		granted := models.Intersection(token.Access, allowed)
+		if len(granted) > 0 {
+			d.Access[token.Label] = granted
+		}
+		later := models.Intersection(token.Access, models.Union(allowed, pending))
+		if !models.IsSubset(later, granted) {
+			interact = true
+		}
+	}
+	if req.Subject != nil {
+		d.Subject = subjectAllowed
+		interact = interact || (!subjectAllowed && subjectPending)
+	}
+	if interact && req.Interact != nil {
+		return Decision{Verdict: Interact}, nil
+	}
+	if len(d.Access) == 0 && !d.Subject {
+		return denial("request_denied"), nil
+	}
+	return d, nil
+}
+
+// requestedTokens lists the access tokens requested by the grant request.
+func requestedTokens(req models.GrantRequest) []models.TokenRequest {
+	if req.AccessToken == nil {
+		return nil
+	}
+	if req.AccessToken.Multiple == nil {
+		return []models.TokenRequest{req.AccessToken.Single}
+	}
+	return req.AccessToken.Multiple
+}
+
+// userMatches reports whether the end user presented in the request, if
+// any, is the identified RO. Users passed by reference are not compared.
+func userMatches(user *models.EndUser, owner Owner) bool {
+	if user == nil || len(user.SubIDs) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(user.SubIDs, func(id subject.ID) bool {
+		return subject.Equal(id, owner.ID)
+	})
+}
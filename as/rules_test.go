@@ -0,0 +1,138 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/bingxueshuang/gnap/models"
+	"github.com/bingxueshuang/gnap/subject"
+)
+
+const testRules = `{"rules": [
+	{"name": "public", "access": [{"type": "photo-api", "actions": ["read"]}]},
+	{"name": "owner", "owners": [{"format": "email", "email": "user@example.com"}], "access": [{"type": "photo-api", "actions": ["read", "write"]}], "subject": true},
+	{"name": "partner", "clients": ["partner"], "access": ["dolphin-metadata"], "interact": true}
+]}`
+
+func TestRulePolicy_Decide(t *testing.T) {
+	registry := &models.AccessRegistry{}
+	_ = registry.Register("dolphin-metadata", models.AccessRight{Type: "dolphin-api", Actions: []string{"read"}})
+	p, err := ParseRules([]byte(testRules), WithAccessRegistry(registry))
+	if err != nil {
+		t.Fatal(err)
+	}
+	email, _ := subject.NewIDEmail("user@example.com")
+	other, _ := subject.NewIDEmail("other@example.com")
+	read := models.AccessRight{Type: "photo-api", Actions: []string{"read"}}
+	write := models.AccessRight{Type: "photo-api", Actions: []string{"write"}}
+	single := func(access ...models.AccessRight) *models.ATRequest {
+		return &models.ATRequest{Single: models.TokenRequest{Access: access}}
+	}
+	interact := &models.IARequest{Start: []models.IAStart{{Mode: models.ModeRedirect}}}
+	tests := []struct {
+		name   string
+		req    models.GrantRequest
+		client ClientIdentity
+		owner  *Owner
+		want   Decision
+	}{
+		{
+			name: "grant",
+			req:  models.GrantRequest{AccessToken: single(read)},
+			want: Decision{Verdict: Grant, Access: map[string][]models.AccessRight{"": {read}}},
+		},
+		{
+			name: "interact for owner rule",
+			req:  models.GrantRequest{AccessToken: single(write), Interact: interact},
+			want: Decision{Verdict: Interact},
+		},
+		{
+			name:  "owner",
+			req:   models.GrantRequest{AccessToken: single(write), Subject: &models.SubRequest{}},
+			owner: &Owner{ID: email},
+			want:  Decision{Verdict: Grant, Access: map[string][]models.AccessRight{"": {write}}, Subject: true},
+		},
+		{
+			name: "downscope without interaction",
+			req:  models.GrantRequest{AccessToken: single(read, write)},
+			want: Decision{Verdict: Grant, Access: map[string][]models.AccessRight{"": {read}}},
+		},
+		{
+			name:  "other owner",
+			req:   models.GrantRequest{AccessToken: single(write)},
+			owner: &Owner{ID: other},
+			want:  denial("request_denied"),
+		},
+		{
+			name:  "user mismatch",
+			req:   models.GrantRequest{AccessToken: single(read), User: &models.EndUser{SubIDs: []subject.ID{other}}},
+			owner: &Owner{ID: email},
+			want:  denial("unknown_user"),
+		},
+		{
+			name:   "reference for client",
+			req:    models.GrantRequest{AccessToken: single(models.AccessRight{Ref: "dolphin-metadata"}), Interact: interact},
+			client: ClientIdentity{ClassID: "partner"},
+			want:   Decision{Verdict: Interact},
+		},
+		{
+			name: "reference for other client",
+			req:  models.GrantRequest{AccessToken: single(models.AccessRight{Ref: "dolphin-metadata"}), Interact: interact},
+			want: denial("request_denied"),
+		},
+		{
+			name: "unknown reference",
+			req:  models.GrantRequest{AccessToken: single(models.AccessRight{Ref: "unknown"})},
+			want: denial("invalid_request"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.Decide(context.Background(), tt.req, tt.client, tt.owner)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RulePolicy.Decide() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	dir := t.TempDir()
+	yamlRules := `
+rules:
+  - name: public
+    access:
+      - type: photo-api
+        actions: [read]
+`
+	tests := []struct {
+		name    string
+		file    string
+		data    string
+		wantErr error
+	}{
+		{name: "json", file: "rules.json", data: testRules},
+		{name: "yaml", file: "rules.yaml", data: yamlRules},
+		{name: "empty rule", file: "empty.json", data: `{"rules": [{"name": "nothing"}]}`, wantErr: ErrInvalidRule},
+		{name: "malformed yaml", file: "bad.yml", data: "rules: [", wantErr: ErrInvalidRule},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.file)
+			if err := os.WriteFile(path, []byte(tt.data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			_, err := LoadRules(path)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("LoadRules() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
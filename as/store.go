@@ -0,0 +1,152 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/bingxueshuang/gnap/models"
+	"golang.org/x/exp/slices"
+)
+
+// ErrNotFound is returned by stores when the requested item does not exist.
+var ErrNotFound = errors.New("not found")
+
+// ErrUnboundToken is returned when a token that is not a bearer token
+// is not bound to a key.
+var ErrUnboundToken = errors.New("access token not bound to a key")
+
+// Token is the record of an access token issued by the AS.
+type Token struct {
+	Value  string
+	Label  string
+	Access []models.AccessRight
+	// Key is the key the token is bound to: the key of the client
+	// instance, unless the token is bound to another key. It is nil only
+	// for bearer tokens.
+	Key        *models.ClientKey
+	Flags      []models.TokenFlag
+	IssuedAt   time.Time
+	ExpiresAt  time.Time // zero value for tokens that do not expire
+	InstanceID string
+}
+
+// Expired reports whether the token has expired at the given time.
+func (t Token) Expired(now time.Time) bool {
+	return !t.ExpiresAt.IsZero() && !now.Before(t.ExpiresAt)
+}
+
+// Bearer reports whether the token is a bearer token, presented
+// without proof of possession of a key.
+func (t Token) Bearer() bool {
+	return slices.Contains(t.Flags, models.FlagBearer)
+}
+
+// Durable reports whether the token remains valid after rotation.
+func (t Token) Durable() bool {
+	return slices.Contains(t.Flags, models.FlagDurable)
+}
+
+// Validate checks the flags of the token against its key. A bearer
+// token must not be bound to a key, in which case the error is the gnap
+// error "invalid_flag". Any other token must be bound to a key, so that
+// the RS can verify its presentation.
+func (t Token) Validate() error {
+	if t.Bearer() && t.Key != nil {
+		return models.GNAPError{Code: "invalid_flag", Desc: "bearer token must not be bound to a key"}
+	}
+	if !t.Bearer() && t.Key == nil {
+		return ErrUnboundToken
+	}
+	return nil
+}
+
+// TokenStore persists the access tokens issued by the AS.
+type TokenStore interface {
+	Get(ctx context.Context, value string) (Token, error)
+	Put(ctx context.Context, token Token) error
+	Delete(ctx context.Context, value string) error
+}
+
+// MemoryTokenStore is a concurrency-safe in-memory [TokenStore].
+// The zero value is ready to use.
+type MemoryTokenStore struct {
+	mu     sync.RWMutex
+	tokens map[string]Token
+}
+
+// Get implements [TokenStore] interface.
+func (s *MemoryTokenStore) Get(ctx context.Context, value string) (Token, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	token, ok := s.tokens[value]
+	if !ok {
+		return Token{}, ErrNotFound
+	}
+	return token, nil
+}
+
+// Put implements [TokenStore] interface. Tokens that fail
+// Token.Validate are rejected.
+func (s *MemoryTokenStore) Put(ctx context.Context, token Token) error {
+	err := token.Validate()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tokens == nil {
+		s.tokens = make(map[string]Token)
+	}
+	s.tokens[token.Value] = token
+	return nil
+}
+
+// Delete implements [TokenStore] interface.
+func (s *MemoryTokenStore) Delete(ctx context.Context, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tokens, value)
+	return nil
+}
+
+// RotateToken replaces the token identified by value with next, as
+// during token rotation, and returns the new token. A new value is
+// generated for next unless it has one. The old token is deleted unless
+// it is durable, in which case it remains valid until it expires.
+func RotateToken(ctx context.Context, store TokenStore, value string, next Token) (Token, error) {
+	old, err := store.Get(ctx, value)
+	if err != nil {
+		return Token{}, err
+	}
+	if next.Value == "" {
+		next.Value, err = NewTokenValue()
+		if err != nil {
+			return Token{}, err
+		}
+	}
+	err = store.Put(ctx, next)
+	if err != nil {
+		return Token{}, err
+	}
+	if old.Durable() {
+		return next, nil
+	}
+	return next, store.Delete(ctx, value)
+}
+
+// Purge deletes the tokens expired at now, and returns how many. It
+// implements [Purger] interface.
+func (s *MemoryTokenStore) Purge(ctx context.Context, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for value, token := range s.tokens {
+		if token.Expired(now) {
+			delete(s.tokens, value)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,75 @@
+package as
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bingxueshuang/gnap/models"
+)
+
+func TestMemoryTokenStore_Put(t *testing.T) {
+	key := &models.ClientKey{Proof: models.ProofHTTPSig, JWK: json.RawMessage(`{"kty":"OKP"}`)}
+	var store MemoryTokenStore
+	err := store.Put(context.Background(), Token{Value: "bound", Key: key})
+	if err != nil {
+		t.Errorf("MemoryTokenStore.Put() error = %v", err)
+	}
+	err = store.Put(context.Background(), Token{Value: "bearer", Key: key, Flags: []models.TokenFlag{models.FlagBearer}})
+	if !errors.Is(err, models.ErrGInvalidFlag) {
+		t.Errorf("MemoryTokenStore.Put() error = %v, want %v", err, models.ErrGInvalidFlag)
+	}
+	if _, err = store.Get(context.Background(), "bearer"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("MemoryTokenStore.Get() error = %v, want %v", err, ErrNotFound)
+	}
+	err = store.Put(context.Background(), Token{Value: "unbound"})
+	if !errors.Is(err, ErrUnboundToken) {
+		t.Errorf("MemoryTokenStore.Put() error = %v, want %v", err, ErrUnboundToken)
+	}
+}
+
+func TestRotateToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		flags   []models.TokenFlag
+		wantOld bool
+	}{
+		{
+			name:    "durable",
+			flags:   []models.TokenFlag{models.FlagDurable},
+			wantOld: true,
+		},
+		{
+			name: "not durable",
+		},
+	}
+	key := &models.ClientKey{Proof: models.ProofHTTPSig, JWK: json.RawMessage(`{"kty":"OKP"}`)}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixedGenerators(t)
+			ctx := context.Background()
+			var store MemoryTokenStore
+			_ = store.Put(ctx, Token{Value: "old", Key: key, Flags: tt.flags})
+			next, err := RotateToken(ctx, &store, "old", Token{Key: key, Flags: tt.flags})
+			if err != nil {
+				t.Fatalf("RotateToken() error = %v", err)
+			}
+			if next.Value != strings.Repeat("H", 40) {
+				t.Errorf("RotateToken() value = %q, want %q", next.Value, strings.Repeat("H", 40))
+			}
+			if _, err := store.Get(ctx, next.Value); err != nil {
+				t.Errorf("MemoryTokenStore.Get(%q) error = %v", next.Value, err)
+			}
+			_, err = store.Get(ctx, "old")
+			if gotOld := err == nil; gotOld != tt.wantOld {
+				t.Errorf("old token kept = %v, want %v", gotOld, tt.wantOld)
+			}
+		})
+	}
+	var store MemoryTokenStore
+	if _, err := RotateToken(context.Background(), &store, "missing", Token{Value: "new", Key: key}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("RotateToken() error = %v, want %v", err, ErrNotFound)
+	}
+}
@@ -0,0 +1,173 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Purger deletes expired records, such as a [GrantStore] or the
+// [MemoryTokenStore].
+type Purger interface {
+	// Purge deletes the records expired at now, and returns how many.
+	Purge(ctx context.Context, now time.Time) (int, error)
+}
+
+// SweepStats counts the records reclaimed by a [Sweeper].
+type SweepStats struct {
+	Grants int
+	Tokens int
+	Limits int
+}
+
+// SweepMetrics describes the work of a [Sweeper] since it was created.
+type SweepMetrics struct {
+	Runs     int
+	Failures int
+	// Reclaimed is the total of records reclaimed by all runs.
+	Reclaimed SweepStats
+	// Last is the outcome of the last run, at LastRun.
+	Last    SweepStats
+	LastRun time.Time
+}
+
+// Sweeper periodically purges the expired grants, and optionally the
+// expired access tokens and limiter entries, so that the stores do not
+// grow without bound.
+type Sweeper struct {
+	grants   GrantStore
+	tokens   Purger
+	limits   Purger
+	interval time.Duration
+	hook     func(SweepStats, error)
+	now      func() time.Time
+
+	mu      sync.Mutex
+	metrics SweepMetrics
+}
+
+// NewSweeper is the constructor for [Sweeper] with mandatory grant store
+// and optional parameters. By default, the sweeper runs every minute.
+func NewSweeper(grants GrantStore, options ...sweeperOption) (s *Sweeper, err error) {
+	s = &Sweeper{grants: grants, interval: time.Minute, now: time.Now}
+	for _, setter := range options {
+		err = setter(s)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// sweeperOption is functional parameter for sweeper constructor.
+type sweeperOption func(*Sweeper) error
+
+// WithSweepInterval is an optional parameter for [NewSweeper] to set the
+// time between runs.
+func WithSweepInterval(interval time.Duration) sweeperOption {
+	return func(s *Sweeper) error {
+		if interval <= 0 {
+			return fmt.Errorf("invalid sweep interval: %v", interval)
+		}
+		s.interval = interval
+		return nil
+	}
+}
+
+// WithTokenPurger is an optional parameter for [NewSweeper] to also
+// purge the expired access tokens, for example of a [MemoryTokenStore].
+func WithTokenPurger(tokens Purger) sweeperOption {
+	return func(s *Sweeper) error {
+		s.tokens = tokens
+		return nil
+	}
+}
+
+// WithLimitPurger is an optional parameter for [NewSweeper] to also
+// purge the stale entries of a [LimitStore], for example of a
+// [MemoryLimitStore].
+func WithLimitPurger(limits Purger) sweeperOption {
+	return func(s *Sweeper) error {
+		s.limits = limits
+		return nil
+	}
+}
+
+// WithSweepHook is an optional parameter for [NewSweeper] to report the
+// outcome of every run, for example to export metrics or log errors.
+func WithSweepHook(hook func(SweepStats, error)) sweeperOption {
+	return func(s *Sweeper) error {
+		s.hook = hook
+		return nil
+	}
+}
+
+// Sweep purges the expired records once. The records reclaimed before
+// an error are still counted.
+func (s *Sweeper) Sweep(ctx context.Context) (SweepStats, error) {
+	now := s.now()
+	var stats SweepStats
+	var errs []error
+	n, err := s.grants.Purge(ctx, now)
+	stats.Grants = n
+	if err != nil {
+		errs = append(errs, fmt.Errorf("purge grants: %w", err))
+	}
+	if s.tokens != nil {
+		n, err = s.tokens.Purge(ctx, now)
+		stats.Tokens = n
+		if err != nil {
+			errs = append(errs, fmt.Errorf("purge tokens: %w", err))
+		}
+	}
+	if s.limits != nil {
+		n, err = s.limits.Purge(ctx, now)
+		stats.Limits = n
+		if err != nil {
+			errs = append(errs, fmt.Errorf("purge limits: %w", err))
+		}
+	}
+	err = errors.Join(errs...)
+
+	s.mu.Lock()
+	s.metrics.Runs++
+	if err != nil {
+		s.metrics.Failures++
+	}
+	s.metrics.Reclaimed.Grants += stats.Grants
+	s.metrics.Reclaimed.Tokens += stats.Tokens
+	s.metrics.Reclaimed.Limits += stats.Limits
+	s.metrics.Last = stats
+	s.metrics.LastRun = now
+	s.mu.Unlock()
+
+	if s.hook != nil {
+		s.hook(stats, err)
+	}
+	return stats, err
+}
+
+// Run sweeps at every interval until the context is cancelled, and
+// returns the error of the context. Errors of single runs do not stop
+// the sweeper; they are reported to the hook and counted in the metrics.
+func (s *Sweeper) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			_, _ = s.Sweep(ctx)
+		}
+	}
+}
+
+// Metrics returns the metrics of the sweeper.
+func (s *Sweeper) Metrics() SweepMetrics {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.metrics
+}
@@ -0,0 +1,87 @@
+package as
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bingxueshuang/gnap/models"
+)
+
+func TestSweeper_Sweep(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	ctx := context.Background()
+	grants := &MemoryGrantStore{}
+	_ = grants.Put(ctx, GrantRecord{ID: "continuing", ContinueToken: "a", ContinueExpiresAt: now.Add(time.Minute)})
+	_ = grants.Put(ctx, GrantRecord{ID: "interacting", ContinueToken: "b", ContinueExpiresAt: now, InteractID: "b", InteractExpiresAt: now.Add(time.Minute)})
+	_ = grants.Put(ctx, GrantRecord{ID: "expired", ContinueToken: "c", ContinueExpiresAt: now, InteractID: "c", InteractExpiresAt: now})
+	_ = grants.Put(ctx, GrantRecord{ID: "finished"})
+	tokens := &MemoryTokenStore{}
+	bearer := []models.TokenFlag{models.FlagBearer}
+	_ = tokens.Put(ctx, Token{Value: "active", Flags: bearer, ExpiresAt: now.Add(time.Hour)})
+	_ = tokens.Put(ctx, Token{Value: "expired", Flags: bearer, ExpiresAt: now})
+
+	limits := &MemoryLimitStore{}
+	_ = limits.SetNotBefore(ctx, "a", now)
+
+	var hooked SweepStats
+	s, err := NewSweeper(grants, WithTokenPurger(tokens), WithLimitPurger(limits), WithSweepHook(func(stats SweepStats, err error) {
+		hooked = stats
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.now = func() time.Time { return now }
+	got, err := s.Sweep(ctx)
+	want := SweepStats{Grants: 2, Tokens: 1, Limits: 1}
+	if err != nil || got != want || hooked != want {
+		t.Errorf("Sweeper.Sweep() = %+v, %v, want %+v", got, err, want)
+	}
+	for id, ok := range map[string]bool{"continuing": true, "interacting": true, "expired": false, "finished": false} {
+		if _, err = grants.Get(ctx, id); (err == nil) != ok {
+			t.Errorf("MemoryGrantStore.Get(%q) error = %v", id, err)
+		}
+	}
+	if _, err = tokens.Get(ctx, "expired"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("MemoryTokenStore.Get() error = %v, want %v", err, ErrNotFound)
+	}
+
+	now = now.Add(time.Minute)
+	_, _ = s.Sweep(ctx)
+	m := s.Metrics()
+	if m.Runs != 2 || m.Reclaimed != (SweepStats{Grants: 4, Tokens: 1, Limits: 1}) || m.Last != (SweepStats{Grants: 2}) || !m.LastRun.Equal(now) {
+		t.Errorf("Sweeper.Metrics() = %+v", m)
+	}
+}
+
+func TestSweeper_Run(t *testing.T) {
+	grants := &MemoryGrantStore{}
+	swept := make(chan SweepStats, 1)
+	s, err := NewSweeper(grants, WithSweepInterval(time.Millisecond), WithSweepHook(func(stats SweepStats, err error) {
+		select {
+		case swept <- stats:
+		default:
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+	select {
+	case <-swept:
+	case <-time.After(time.Second):
+		t.Fatal("Sweeper.Run() did not sweep")
+	}
+	cancel()
+	select {
+	case err = <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Sweeper.Run() error = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Sweeper.Run() did not stop")
+	}
+}
@@ -0,0 +1,8 @@
+// Package client provides building blocks for a GNAP client instance.
+//
+// The package offers helpers to use the access tokens granted by the AS,
+// such as a [net/http] transport that presents an access token to the RS
+// along with the proof of possession of the key it is bound to, and keeps
+// the token fresh through the token management API, and a store of the
+// tokens held by the client instance indexed by their label.
+package client // import "github.com/bingxueshuang/gnap/client"
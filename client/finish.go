@@ -0,0 +1,36 @@
+package client
+
+import (
+	"sync/atomic"
+
+	"github.com/bingxueshuang/gnap/models"
+	"github.com/bingxueshuang/gnap/nonce"
+)
+
+// nonces generates the nonces of the client instance.
+var nonces atomic.Pointer[nonce.Generator]
+
+func init() {
+	SetNonceGenerator(nil)
+}
+
+// SetNonceGenerator sets the generator of the nonces of the client
+// instance, for example one with a deterministic source in tests. A nil
+// generator restores the default generator of [nonce.New].
+func SetNonceGenerator(g *nonce.Generator) {
+	if g == nil {
+		g = nonce.MustNew()
+	}
+	nonces.Store(g)
+}
+
+// NewFinish creates the interaction finish parameters of a grant
+// request, with a newly generated nonce. The nonce must be kept to
+// verify the interaction hash when interaction finishes.
+func NewFinish(method models.FinishMethod, uri models.URL) (models.IAFinish, error) {
+	n, err := nonces.Load().Generate()
+	if err != nil {
+		return models.IAFinish{}, err
+	}
+	return models.IAFinish{Method: method, URI: &uri, Nonce: n}, nil
+}
@@ -0,0 +1,25 @@
+package client
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bingxueshuang/gnap/models"
+	"github.com/bingxueshuang/gnap/nonce"
+)
+
+func TestNewFinish(t *testing.T) {
+	SetNonceGenerator(nonce.MustNew(nonce.WithReader(bytes.NewReader(bytes.Repeat([]byte{11}, nonce.DefaultLength)))))
+	t.Cleanup(func() { SetNonceGenerator(nil) })
+	uri, _ := models.ParseURL("https://client.example.net/return/123455")
+	got, err := NewFinish(models.MethodRedirect, uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Nonce != "LLLLLLLLLLLLLLLLLLLL" {
+		t.Errorf("NewFinish() nonce = %v, want LLLLLLLLLLLLLLLLLLLL", got.Nonce)
+	}
+	if err = got.Validate(); err != nil {
+		t.Errorf("IAFinish.Validate() error = %v", err)
+	}
+}
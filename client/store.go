@@ -0,0 +1,298 @@
+package client
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/bingxueshuang/gnap/models"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// Errors of the token store.
+var (
+	ErrNotFound       = errors.New("not found")
+	ErrCorruptStore   = errors.New("corrupt token store file")
+	ErrDuplicateLabel = errors.New("duplicate token label")
+)
+
+// StoredToken is an access token held by the client instance, along
+// with the time it was issued.
+type StoredToken struct {
+	Token    models.TokenResponse `json:"token"`
+	IssuedAt time.Time            `json:"issued_at"`
+}
+
+// Expiry returns the time at which the token expires, computed from
+// ExpiresIn relative to issuance. It is the zero value for tokens that
+// do not expire.
+func (st StoredToken) Expiry() time.Time {
+	return expiryOf(st.Token, st.IssuedAt)
+}
+
+// Expired reports whether the token has expired at the given time.
+func (st StoredToken) Expired(now time.Time) bool {
+	expiry := st.Expiry()
+	return !expiry.IsZero() && !now.Before(expiry)
+}
+
+// TokenStore is a concurrency-safe store of the access tokens held by
+// the client instance, indexed by their label. A token without label,
+// as issued for a single access token request, is stored under the
+// empty label. The store optionally persists the tokens to a local file,
+// encrypted with AES-GCM.
+type TokenStore struct {
+	path string
+	aead cipher.AEAD
+	now  func() time.Time
+
+	mu     sync.RWMutex
+	tokens map[string]StoredToken
+}
+
+// NewTokenStore is the constructor for [TokenStore] with optional
+// parameters.
+func NewTokenStore(options ...tokenStoreOption) (s *TokenStore, err error) {
+	s = &TokenStore{now: time.Now, tokens: make(map[string]StoredToken)}
+	for _, setter := range options {
+		err = setter(s)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if s.path == "" {
+		return s, nil
+	}
+	err = s.load()
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// tokenStoreOption is functional parameter for token store constructor.
+type tokenStoreOption func(*TokenStore) error
+
+// WithFile is an optional parameter for [NewTokenStore] to persist the
+// tokens to the file at path, encrypted with the AES key, which must be
+// 16, 24 or 32 bytes long. Tokens already in the file are loaded.
+func WithFile(path string, key []byte) tokenStoreOption {
+	return func(s *TokenStore) error {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return err
+		}
+		s.path = path
+		s.aead = aead
+		return nil
+	}
+}
+
+// Put stores the access token, issued now, under its label, replacing
+// any token of the same label.
+func (s *TokenStore) Put(token models.TokenResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tokens := maps.Clone(s.tokens)
+	tokens[token.Label] = StoredToken{Token: token, IssuedAt: s.now()}
+	return s.commit(tokens)
+}
+
+// PutAll stores all the access tokens of the grant response, issued
+// now. Multiple access tokens must carry distinct labels.
+func (s *TokenStore) PutAll(res models.ATResponse) error {
+	tokens := res.Multiple
+	if tokens == nil {
+		tokens = []models.TokenResponse{res.Single}
+	}
+	labels := make(map[string]bool, len(tokens))
+	for _, token := range tokens {
+		if labels[token.Label] {
+			return fmt.Errorf("%w: %q", ErrDuplicateLabel, token.Label)
+		}
+		labels[token.Label] = true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	next := maps.Clone(s.tokens)
+	for _, token := range tokens {
+		next[token.Label] = StoredToken{Token: token, IssuedAt: now}
+	}
+	return s.commit(next)
+}
+
+// Get returns the token stored under the label, even if it has expired.
+func (s *TokenStore) Get(label string) (StoredToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	st, ok := s.tokens[label]
+	if !ok {
+		return StoredToken{}, ErrNotFound
+	}
+	return st, nil
+}
+
+// Lookup returns an unexpired token whose access covers every right in
+// access. When several tokens qualify, the one with the lexically first
+// label is returned.
+func (s *TokenStore) Lookup(access []models.AccessRight) (StoredToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	now := s.now()
+	labels := maps.Keys(s.tokens)
+	slices.Sort(labels)
+	for _, label := range labels {
+		st := s.tokens[label]
+		if !st.Expired(now) && models.IsSubset(access, st.Token.Access) {
+			return st, nil
+		}
+	}
+	return StoredToken{}, ErrNotFound
+}
+
+// Labels returns the labels of the stored tokens in lexical order.
+func (s *TokenStore) Labels() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	labels := maps.Keys(s.tokens)
+	slices.Sort(labels)
+	return labels
+}
+
+// Delete removes the token stored under the label.
+func (s *TokenStore) Delete(label string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.tokens[label]; !ok {
+		return nil
+	}
+	tokens := maps.Clone(s.tokens)
+	delete(tokens, label)
+	return s.commit(tokens)
+}
+
+// Purge removes the expired tokens that cannot be rotated, as they have
+// no management URI. It returns the number of tokens removed.
+func (s *TokenStore) Purge() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	tokens := maps.Clone(s.tokens)
+	for label, st := range tokens {
+		if st.Expired(now) && st.Token.Manage == nil {
+			delete(tokens, label)
+		}
+	}
+	n := len(s.tokens) - len(tokens)
+	if n == 0 {
+		return 0, nil
+	}
+	err := s.commit(tokens)
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// Transport creates a [Transport] presenting the token stored under the
+// label. Rotated tokens are written back to the store under the same
+// label, and a failure to store them is reported by the transport.
+func (s *TokenStore) Transport(label string, options ...transportOption) (*Transport, error) {
+	st, err := s.Get(label)
+	if err != nil {
+		return nil, err
+	}
+	options = append(options,
+		WithIssuedAt(st.IssuedAt),
+		WithRotateHook(func(token models.TokenResponse) error {
+			if token.Label == "" {
+				token.Label = label
+			}
+			return s.Put(token)
+		}),
+	)
+	return NewTransport(st.Token, options...)
+}
+
+// load reads the tokens from the file. A missing file holds no tokens.
+func (s *TokenStore) load() error {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	size := s.aead.NonceSize()
+	if len(data) < size {
+		return ErrCorruptStore
+	}
+	plain, err := s.aead.Open(nil, data[:size], data[size:], nil)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCorruptStore, err)
+	}
+	err = json.Unmarshal(plain, &s.tokens)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCorruptStore, err)
+	}
+	return nil
+}
+
+// commit writes the tokens to the file, if any, and then makes them the
+// tokens of the store, which are left untouched if the write fails.
+// Caller must hold the lock.
+func (s *TokenStore) commit(tokens map[string]StoredToken) error {
+	err := s.save(tokens)
+	if err != nil {
+		return err
+	}
+	s.tokens = tokens
+	return nil
+}
+
+// save writes the tokens to the file, if any, replacing it atomically.
+func (s *TokenStore) save(tokens map[string]StoredToken) error {
+	if s.path == "" {
+		return nil
+	}
+	plain, err := json.Marshal(tokens)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, s.aead.NonceSize())
+	_, err = io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return err
+	}
+	data := s.aead.Seal(nonce, nonce, plain, nil)
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,194 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bingxueshuang/gnap/models"
+)
+
+func TestTokenStore_Lookup(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	s, err := NewTokenStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.now = func() time.Time { return now.Add(-time.Hour) }
+	err = s.PutAll(models.ATResponse{Multiple: []models.TokenResponse{
+		{Value: "a", Label: "read", Access: []models.AccessRight{{Type: "photo-api", Actions: []string{"read"}}}},
+		{Value: "b", Label: "write", Access: []models.AccessRight{{Type: "photo-api", Actions: []string{"read", "write"}}}},
+		{Value: "c", Label: "expired", ExpiresIn: 60, Access: []models.AccessRight{{Type: "photo-api", Actions: []string{"delete"}}}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.now = func() time.Time { return now }
+	tests := []struct {
+		name    string
+		access  []models.AccessRight
+		want    string
+		wantErr error
+	}{
+		{name: "first label", access: []models.AccessRight{{Type: "photo-api", Actions: []string{"read"}}}, want: "a"},
+		{name: "covering", access: []models.AccessRight{{Type: "photo-api", Actions: []string{"write"}}}, want: "b"},
+		{name: "expired", access: []models.AccessRight{{Type: "photo-api", Actions: []string{"delete"}}}, wantErr: ErrNotFound},
+		{name: "uncovered", access: []models.AccessRight{{Type: "video-api"}}, wantErr: ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Lookup(tt.access)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("TokenStore.Lookup() error = %v, want %v", err, tt.wantErr)
+			}
+			if got.Token.Value != tt.want {
+				t.Errorf("TokenStore.Lookup() = %v, want %v", got.Token.Value, tt.want)
+			}
+		})
+	}
+	n, err := s.Purge()
+	if err != nil || n != 1 {
+		t.Errorf("TokenStore.Purge() = %v, %v, want 1", n, err)
+	}
+	if got := s.Labels(); strings.Join(got, ",") != "read,write" {
+		t.Errorf("TokenStore.Labels() = %v, want [read write]", got)
+	}
+}
+
+func TestTokenStore_PutAll(t *testing.T) {
+	s, _ := NewTokenStore()
+	err := s.PutAll(models.ATResponse{Multiple: []models.TokenResponse{
+		{Value: "a", Label: "x", Access: []models.AccessRight{{Ref: "a"}}},
+		{Value: "b", Label: "x", Access: []models.AccessRight{{Ref: "b"}}},
+	}})
+	if !errors.Is(err, ErrDuplicateLabel) {
+		t.Errorf("TokenStore.PutAll() error = %v, want %v", err, ErrDuplicateLabel)
+	}
+	if _, err = s.Get("x"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("TokenStore.Get() error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestTokenStore_file(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens")
+	key := bytes.Repeat([]byte{7}, 32)
+	s, err := NewTokenStore(WithFile(path, key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := models.TokenResponse{Value: "OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0", Label: "photos", ExpiresIn: 3600, Access: []models.AccessRight{{Ref: "dolphin-metadata"}}}
+	if err = s.Put(token); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte(token.Value)) {
+		t.Error("TokenStore file holds token in plain text")
+	}
+	loaded, err := NewTokenStore(WithFile(path, key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := loaded.Get("photos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := s.Get("photos")
+	if got.Token.Value != token.Value || !got.IssuedAt.Equal(want.IssuedAt) {
+		t.Errorf("TokenStore.Get() = %+v, want %+v", got, want)
+	}
+	_, err = NewTokenStore(WithFile(path, bytes.Repeat([]byte{8}, 32)))
+	if !errors.Is(err, ErrCorruptStore) {
+		t.Errorf("NewTokenStore() error = %v, want %v", err, ErrCorruptStore)
+	}
+}
+
+func TestTokenStore_Transport(t *testing.T) {
+	signer := testSigner(t, models.ProofHTTPSig)
+	var rotations atomic.Int32
+	srv := rotationServer(t, signer, &rotations)
+	defer srv.Close()
+	manage, _ := models.ParseURL(srv.URL + "/manage")
+	s, _ := NewTokenStore()
+	_ = s.Put(models.TokenResponse{
+		Value:  "old",
+		Label:  "photos",
+		Manage: &models.TokenManage{URI: manage, Token: models.ContinueToken{Value: "manage"}},
+	})
+	tr, err := s.Transport("photos", WithSigner(signer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/resource", nil)
+	res, err := (&http.Client{Transport: tr}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	got, err := s.Get("photos")
+	if err != nil || got.Token.Value != "new" {
+		t.Errorf("TokenStore.Get() = %v, %v, want rotated token", got.Token.Value, err)
+	}
+}
+
+func TestTokenStore_failedSave(t *testing.T) {
+	signer := testSigner(t, models.ProofHTTPSig)
+	var rotations atomic.Int32
+	srv := rotationServer(t, signer, &rotations)
+	defer srv.Close()
+	manage, _ := models.ParseURL(srv.URL + "/manage")
+	dir := filepath.Join(t.TempDir(), "store")
+	if err := os.Mkdir(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewTokenStore(WithFile(filepath.Join(dir, "tokens"), bytes.Repeat([]byte{7}, 32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Put(models.TokenResponse{
+		Value:  "old",
+		Label:  "photos",
+		Manage: &models.TokenManage{URI: manage, Token: models.ContinueToken{Value: "manage"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, err := s.Transport("photos", WithSigner(signer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Put(models.TokenResponse{Value: "a", Label: "videos"}); err == nil {
+		t.Error("TokenStore.Put() error = nil, want failed save")
+	}
+	if err = s.PutAll(models.ATResponse{Single: models.TokenResponse{Value: "b", Label: "photos"}}); err == nil {
+		t.Error("TokenStore.PutAll() error = nil, want failed save")
+	}
+	if err = s.Delete("photos"); err == nil {
+		t.Error("TokenStore.Delete() error = nil, want failed save")
+	}
+	if got := s.Labels(); strings.Join(got, ",") != "photos" {
+		t.Errorf("TokenStore.Labels() = %v, want [photos]", got)
+	}
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/resource", nil)
+	_, err = (&http.Client{Transport: tr}).Do(req)
+	if !errors.Is(err, ErrRotateHook) {
+		t.Errorf("Transport.RoundTrip() error = %v, want %v", err, ErrRotateHook)
+	}
+	if got := tr.Token().Value; got != "new" {
+		t.Errorf("Transport.Token() = %v, want new", got)
+	}
+	if got, _ := s.Get("photos"); got.Token.Value != "old" {
+		t.Errorf("TokenStore.Get() = %v, want old", got.Token.Value)
+	}
+}
@@ -0,0 +1,359 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bingxueshuang/gnap/models"
+	"github.com/bingxueshuang/gnap/proof"
+	"golang.org/x/exp/slices"
+)
+
+// Errors of the token presentation transport.
+var (
+	ErrMissingSigner = errors.New("missing signer for key-bound token")
+	ErrTokenExpired  = errors.New("access token expired")
+	ErrRotateHook    = errors.New("rotate hook failed")
+)
+
+// Transport is a [http.RoundTripper] that presents an access token with
+// each request. Key-bound tokens are presented along with the proof of
+// possession of the key, using the proof method of the key, while bearer
+// tokens are presented as is. Tokens that have expired, or that the RS
+// rejects, are rotated through the token management API when available.
+type Transport struct {
+	base      http.RoundTripper
+	signer    *proof.Signer
+	keySigner *proof.Signer
+	onRotate  func(models.TokenResponse) error
+	issuedAt  time.Time
+	now       func() time.Time
+
+	mu       sync.Mutex
+	token    models.TokenResponse
+	expiry   time.Time     // zero value for tokens that do not expire
+	rotation *rotationCall // rotation in flight, if any
+}
+
+// rotationCall is a rotation in flight, which concurrent callers wait
+// for instead of rotating the token again.
+type rotationCall struct {
+	done  chan struct{}
+	token models.TokenResponse
+	err   error
+}
+
+// NewTransport is the constructor for [Transport] with mandatory access
+// token and optional parameters. Unless the token is a bearer token, a
+// signer for the key it is bound to must be given.
+func NewTransport(token models.TokenResponse, options ...transportOption) (t *Transport, err error) {
+	t = &Transport{
+		base:  http.DefaultTransport,
+		now:   time.Now,
+		token: token,
+	}
+	for _, setter := range options {
+		err = setter(t)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if t.issuedAt.IsZero() {
+		t.issuedAt = t.now()
+	}
+	t.expiry = expiryOf(token, t.issuedAt)
+	if t.signerFor(token) == nil && !token.Bearer() {
+		return nil, ErrMissingSigner
+	}
+	return t, nil
+}
+
+// transportOption is functional parameter for transport constructor.
+type transportOption func(*Transport) error
+
+// WithBase is an optional parameter for [NewTransport] to set the
+// underlying transport. Default is [http.DefaultTransport].
+func WithBase(base http.RoundTripper) transportOption {
+	return func(t *Transport) error {
+		t.base = base
+		return nil
+	}
+}
+
+// WithSigner is an optional parameter for [NewTransport] to set the
+// signer for the key of the client instance. It signs requests with tokens
+// bound to the client key, and calls to the token management API.
+func WithSigner(signer *proof.Signer) transportOption {
+	return func(t *Transport) error {
+		t.signer = signer
+		return nil
+	}
+}
+
+// WithTokenSigner is an optional parameter for [NewTransport] to set the
+// signer for the Key of the token, for tokens bound to a key other than
+// the key of the client instance.
+func WithTokenSigner(signer *proof.Signer) transportOption {
+	return func(t *Transport) error {
+		t.keySigner = signer
+		return nil
+	}
+}
+
+// WithIssuedAt is an optional parameter for [NewTransport] to set when
+// the token was issued, from which its expiry is computed. Default is
+// the time of construction.
+func WithIssuedAt(issuedAt time.Time) transportOption {
+	return func(t *Transport) error {
+		t.issuedAt = issuedAt
+		return nil
+	}
+}
+
+// WithRotateHook is an optional parameter for [NewTransport] to be
+// notified of the new token after each rotation, typically to persist it.
+// An error of the hook fails the call that rotated the token with
+// [ErrRotateHook], though the transport presents the new token from then
+// on.
+func WithRotateHook(fn func(models.TokenResponse) error) transportOption {
+	return func(t *Transport) error {
+		t.onRotate = fn
+		return nil
+	}
+}
+
+// Token returns the access token currently presented by the transport.
+func (t *Transport) Token() models.TokenResponse {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.token
+}
+
+// RoundTrip implements [http.RoundTripper] interface. If the RS responds
+// with status 401, the token is rotated and the request retried once,
+// provided the request body can be replayed.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.current(req.Context())
+	if err != nil {
+		closeBody(req)
+		return nil, err
+	}
+	res, err := t.send(req, token, false)
+	if err != nil || res.StatusCode != http.StatusUnauthorized {
+		return res, err
+	}
+	if token.Manage == nil || !replayable(req) {
+		return res, nil
+	}
+	next, err := t.rotate(req.Context(), token.Value)
+	if errors.Is(err, ErrRotateHook) {
+		_ = res.Body.Close()
+		return nil, err
+	}
+	if err != nil {
+		return res, nil // the response of the RS is more telling
+	}
+	_ = res.Body.Close()
+	return t.send(req, next, true)
+}
+
+// Rotate replaces the access token with a new one issued through the
+// token management API.
+func (t *Transport) Rotate(ctx context.Context) error {
+	t.mu.Lock()
+	value := t.token.Value
+	t.mu.Unlock()
+	_, err := t.rotate(ctx, value)
+	return err
+}
+
+// current returns the access token to present, rotating it first if it
+// has expired.
+func (t *Transport) current(ctx context.Context) (models.TokenResponse, error) {
+	t.mu.Lock()
+	token, expiry := t.token, t.expiry
+	t.mu.Unlock()
+	if expiry.IsZero() || t.now().Before(expiry) {
+		return token, nil
+	}
+	if token.Manage == nil {
+		return models.TokenResponse{}, ErrTokenExpired
+	}
+	token, err := t.rotate(ctx, token.Value)
+	if errors.Is(err, ErrRotateHook) {
+		return models.TokenResponse{}, err
+	}
+	if err != nil {
+		return models.TokenResponse{}, fmt.Errorf("%w: %w", ErrTokenExpired, err)
+	}
+	return token, nil
+}
+
+// send presents the token with a copy of the request. On retry, the body
+// is obtained afresh from the request.
+func (t *Transport) send(req *http.Request, token models.TokenResponse, retry bool) (*http.Response, error) {
+	r := req.Clone(req.Context())
+	if retry && req.Body != nil && req.Body != http.NoBody {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = body
+	}
+	r.Header.Set("Authorization", "GNAP "+token.Value)
+	if !token.Bearer() {
+		signer := t.signerFor(token)
+		if signer == nil {
+			closeBody(r)
+			return nil, ErrMissingSigner
+		}
+		err := signer.Sign(r)
+		if err != nil {
+			closeBody(r)
+			return nil, err
+		}
+	}
+	return t.base.RoundTrip(r)
+}
+
+// signerFor returns the signer for the key the token is bound to.
+func (t *Transport) signerFor(token models.TokenResponse) *proof.Signer {
+	if token.Key != nil {
+		return t.keySigner
+	}
+	return t.signer
+}
+
+// rotationResponse is the response of the token management API.
+type rotationResponse struct {
+	AccessToken models.TokenResponse `json:"access_token"`
+}
+
+// rotate calls the token management API to rotate the token identified
+// by stale. If the token has already been rotated by a concurrent call,
+// the current token is returned instead, and if a rotation is in flight,
+// its outcome. The lock is not held during the call to the API.
+func (t *Transport) rotate(ctx context.Context, stale string) (models.TokenResponse, error) {
+	t.mu.Lock()
+	if t.token.Value != stale {
+		token := t.token
+		t.mu.Unlock()
+		return token, nil
+	}
+	if c := t.rotation; c != nil {
+		t.mu.Unlock()
+		select {
+		case <-c.done:
+			return c.token, c.err
+		case <-ctx.Done():
+			return models.TokenResponse{}, ctx.Err()
+		}
+	}
+	c := &rotationCall{done: make(chan struct{})}
+	t.rotation = c
+	token := t.token
+	t.mu.Unlock()
+
+	defer func() {
+		t.mu.Lock()
+		t.rotation = nil
+		t.mu.Unlock()
+		close(c.done)
+	}()
+	var expiry time.Time
+	c.token, expiry, c.err = t.exchange(ctx, token)
+	if c.err != nil {
+		return models.TokenResponse{}, c.err
+	}
+	t.mu.Lock()
+	t.token = c.token
+	t.expiry = expiry
+	t.mu.Unlock()
+	if t.onRotate == nil {
+		return c.token, nil
+	}
+	err := t.onRotate(c.token)
+	if err != nil {
+		return models.TokenResponse{}, fmt.Errorf("%w: %w", ErrRotateHook, err)
+	}
+	return c.token, nil
+}
+
+// exchange obtains a new token for the given one from the token
+// management API, and returns it with its expiry. Without a management
+// access token, as decoded from a draft-13 message, the call is
+// authorized with the managed access token itself, as in draft-13.
+func (t *Transport) exchange(ctx context.Context, token models.TokenResponse) (models.TokenResponse, time.Time, error) {
+	manage := token.Manage
+	if manage == nil || manage.URI.URL == nil {
+		return models.TokenResponse{}, time.Time{}, fmt.Errorf("rotation: %w", models.ErrMissingField)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, manage.URI.String(), nil)
+	if err != nil {
+		return models.TokenResponse{}, time.Time{}, err
+	}
+	req.Header.Set("Accept", "application/json")
+	value, signer := manage.Token.Value, t.signer
+	bearer := slices.Contains(manage.Token.Flags, models.FlagBearer)
+	if value == "" {
+		value, signer, bearer = token.Value, t.signerFor(token), token.Bearer()
+	}
+	req.Header.Set("Authorization", "GNAP "+value)
+	if !bearer {
+		if signer == nil {
+			return models.TokenResponse{}, time.Time{}, ErrMissingSigner
+		}
+		err = signer.Sign(req)
+		if err != nil {
+			return models.TokenResponse{}, time.Time{}, err
+		}
+	}
+	issuedAt := t.now()
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return models.TokenResponse{}, time.Time{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return models.TokenResponse{}, time.Time{}, fmt.Errorf("rotation: %w", models.ReadError(resp))
+	}
+	var res rotationResponse
+	err = json.NewDecoder(resp.Body).Decode(&res)
+	if err == nil {
+		err = res.AccessToken.Validate()
+	}
+	if err != nil {
+		return models.TokenResponse{}, time.Time{}, fmt.Errorf("rotation: %w", err)
+	}
+	next := res.AccessToken
+	if t.signerFor(next) == nil && !next.Bearer() {
+		return models.TokenResponse{}, time.Time{}, ErrMissingSigner
+	}
+	return next, expiryOf(next, issuedAt), nil
+}
+
+// expiryOf computes the expiry of the token issued at the given time.
+func expiryOf(token models.TokenResponse, issuedAt time.Time) time.Time {
+	if token.ExpiresIn <= 0 {
+		return time.Time{}
+	}
+	return issuedAt.Add(time.Duration(token.ExpiresIn) * time.Second)
+}
+
+// replayable reports whether the request can be sent again.
+func replayable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// closeBody closes the request body, as required of a round tripper
+// even when it fails.
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+}
@@ -0,0 +1,306 @@
+package client
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bingxueshuang/gnap/models"
+	"github.com/bingxueshuang/gnap/proof"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+)
+
+// testSigner creates a signer for a fresh ed25519 key with the given
+// proof method.
+func testSigner(t *testing.T, method models.ProofMethod) *proof.Signer {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := jwk.FromRaw(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := k.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := json.Marshal(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var proofer models.Proofer = method
+	if method == models.ProofHTTPSig {
+		proofer = models.HTTPSig{Method: method, SigAlg: models.ED25519}
+	}
+	signer, err := proof.NewSigner(models.ClientKey{Proof: proofer, JWK: raw}, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+func TestTransport_RoundTrip(t *testing.T) {
+	access := []models.AccessRight{{Ref: "photo-api"}}
+	tests := []struct {
+		name   string
+		method models.ProofMethod
+		flags  []models.TokenFlag
+		bound  bool
+		body   string
+	}{
+		{name: "bearer", flags: []models.TokenFlag{models.FlagBearer}, body: `{"photo":"a"}`},
+		{name: "httpsig", method: models.ProofHTTPSig, body: `{"photo":"a"}`},
+		{name: "httpsig without body", method: models.ProofHTTPSig},
+		{name: "jwsd", method: models.ProofJWSD, body: `{"photo":"a"}`},
+		{name: "jws", method: models.ProofJWS, body: `{"photo":"a"}`},
+		{name: "token key", method: models.ProofJWSD, bound: true, body: `{"photo":"a"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := models.TokenResponse{Value: "OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0", Access: access, Flags: tt.flags}
+			var options []transportOption
+			var signer *proof.Signer
+			if tt.method != "" {
+				signer = testSigner(t, tt.method)
+				if tt.bound {
+					key := signer.Key()
+					token.Key = &key
+					options = append(options, WithTokenSigner(signer))
+				} else {
+					options = append(options, WithSigner(signer))
+				}
+			}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := proof.AccessToken(r); got != token.Value {
+					t.Errorf("Transport.RoundTrip() token = %v, want %v", got, token.Value)
+				}
+				if signer == nil {
+					if r.Header.Get("Signature") != "" || r.Header.Get("Detached-JWS") != "" {
+						t.Error("Transport.RoundTrip() signed bearer token request")
+					}
+				} else if err := proof.Verify(r, signer.Key()); err != nil {
+					t.Errorf("proof.Verify() error = %v", err)
+				}
+				body, _ := io.ReadAll(r.Body)
+				if string(body) != tt.body {
+					t.Errorf("Transport.RoundTrip() body = %s, want %s", body, tt.body)
+				}
+			}))
+			defer srv.Close()
+			tr, err := NewTransport(token, options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var body io.Reader
+			if tt.body != "" {
+				body = strings.NewReader(tt.body)
+			}
+			req, _ := http.NewRequest(http.MethodPost, srv.URL+"/photos", body)
+			res, err := (&http.Client{Transport: tr}).Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+			if res.StatusCode != http.StatusOK {
+				t.Errorf("Transport.RoundTrip() status = %v, want %v", res.StatusCode, http.StatusOK)
+			}
+		})
+	}
+}
+
+// rotationServer serves a resource that accepts only the token "new",
+// and a management endpoint rotating the token "old" into "new".
+func rotationServer(t *testing.T, signer *proof.Signer, rotations *atomic.Int32) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/resource", func(w http.ResponseWriter, r *http.Request) {
+		if proof.AccessToken(r) != "new" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(body)
+	})
+	mux.HandleFunc("/manage", func(w http.ResponseWriter, r *http.Request) {
+		// draft-13 peers authorize with the managed access token "old"
+		token := proof.AccessToken(r)
+		if err := proof.Verify(r, signer.Key()); err != nil || (token != "manage" && token != "old") {
+			models.GNAPError{Code: "invalid_request"}.WriteResponse(w)
+			return
+		}
+		rotations.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"access_token":{"value":"new","access":["photo-api"],"expires_in":3600}}`)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestTransport_rotate(t *testing.T) {
+	signer := testSigner(t, models.ProofHTTPSig)
+	var rotations atomic.Int32
+	srv := rotationServer(t, signer, &rotations)
+	defer srv.Close()
+	manage, _ := models.ParseURL(srv.URL + "/manage")
+	issued := time.Unix(1700000000, 0)
+	tests := []struct {
+		name    string
+		token   models.TokenResponse
+		now     time.Time
+		want    string
+		wantErr error
+	}{
+		{
+			name:  "expired",
+			token: models.TokenResponse{Value: "old", ExpiresIn: 60, Manage: &models.TokenManage{URI: manage, Token: models.ContinueToken{Value: "manage"}}},
+			now:   issued.Add(time.Minute),
+			want:  "new",
+		},
+		{
+			name:  "rejected",
+			token: models.TokenResponse{Value: "old", Manage: &models.TokenManage{URI: manage, Token: models.ContinueToken{Value: "manage"}}},
+			now:   issued,
+			want:  "new",
+		},
+		{
+			name:  "draft-13 manage",
+			token: models.TokenResponse{Value: "old", ExpiresIn: 60, Manage: &models.TokenManage{URI: manage}},
+			now:   issued.Add(time.Minute),
+			want:  "new",
+		},
+		{
+			name:    "draft-13 manage of other token",
+			token:   models.TokenResponse{Value: "stolen", ExpiresIn: 60, Manage: &models.TokenManage{URI: manage}},
+			now:     issued.Add(time.Minute),
+			wantErr: ErrTokenExpired,
+		},
+		{
+			name:    "expired unmanaged",
+			token:   models.TokenResponse{Value: "old", ExpiresIn: 60},
+			now:     issued.Add(time.Hour),
+			wantErr: ErrTokenExpired,
+		},
+		{
+			name:  "rejected unmanaged",
+			token: models.TokenResponse{Value: "old"},
+			now:   issued,
+			want:  "old",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rotated []string
+			tr, err := NewTransport(tt.token, WithSigner(signer), WithIssuedAt(issued), WithRotateHook(func(token models.TokenResponse) error {
+				rotated = append(rotated, token.Value)
+				return nil
+			}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tr.now = func() time.Time { return tt.now }
+			req, _ := http.NewRequest(http.MethodPost, srv.URL+"/resource", strings.NewReader("photo"))
+			res, err := (&http.Client{Transport: tr}).Do(req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Transport.RoundTrip() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			res.Body.Close()
+			if got := tr.Token().Value; got != tt.want {
+				t.Errorf("Transport.Token() = %v, want %v", got, tt.want)
+			}
+			if tt.want == "new" && (res.StatusCode != http.StatusOK || len(rotated) != 1) {
+				t.Errorf("Transport.RoundTrip() status = %v, rotations = %v", res.StatusCode, rotated)
+			}
+		})
+	}
+}
+
+func TestTransport_rotate_concurrent(t *testing.T) {
+	signer := testSigner(t, models.ProofHTTPSig)
+	var rotations atomic.Int32
+	srv := rotationServer(t, signer, &rotations)
+	defer srv.Close()
+	started, release := make(chan struct{}), make(chan struct{})
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path == "/manage" {
+			close(started)
+			<-release
+		}
+		return http.DefaultTransport.RoundTrip(req)
+	})
+	manage, _ := models.ParseURL(srv.URL + "/manage")
+	token := models.TokenResponse{Value: "old", Manage: &models.TokenManage{URI: manage, Token: models.ContinueToken{Value: "manage"}}}
+	tr, err := NewTransport(token, WithSigner(signer), WithBase(base))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := tr.rotate(context.Background(), "old")
+			errs <- err
+		}()
+	}
+	<-started
+	if got := tr.Token().Value; got != "old" {
+		t.Errorf("Transport.Token() during rotation = %v, want old", got)
+	}
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Transport.rotate() error = %v", err)
+		}
+	}
+	if got := rotations.Load(); got != 1 || tr.Token().Value != "new" {
+		t.Errorf("rotations = %d, token = %v, want 1 rotation to new", got, tr.Token().Value)
+	}
+}
+
+// roundTripFunc adapts a function to [http.RoundTripper].
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestNewTransport(t *testing.T) {
+	signer := testSigner(t, models.ProofHTTPSig)
+	key := signer.Key()
+	tests := []struct {
+		name    string
+		token   models.TokenResponse
+		options []transportOption
+		wantErr error
+	}{
+		{name: "bearer", token: models.TokenResponse{Value: "x", Flags: []models.TokenFlag{models.FlagBearer}}},
+		{name: "bound", token: models.TokenResponse{Value: "x"}, options: []transportOption{WithSigner(signer)}},
+		{name: "bound without signer", token: models.TokenResponse{Value: "x"}, wantErr: ErrMissingSigner},
+		{name: "token key without signer", token: models.TokenResponse{Value: "x", Key: &key}, options: []transportOption{WithSigner(signer)}, wantErr: ErrMissingSigner},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewTransport(tt.token, tt.options...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("NewTransport() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -3,20 +3,21 @@ module github.com/bingxueshuang/gnap
 go 1.20
 
 require (
+	github.com/dunglas/httpsfv v1.0.1
+	github.com/lestrrat-go/jwx/v2 v2.0.6
 	github.com/yaronf/httpsign v0.1.15
 	golang.org/x/crypto v0.8.0
 	golang.org/x/exp v0.0.0-20230425010034-47ecfdc1ba53
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.1.0 // indirect
-	github.com/dunglas/httpsfv v1.0.1 // indirect
 	github.com/goccy/go-json v0.9.11 // indirect
 	github.com/lestrrat-go/blackmagic v1.0.1 // indirect
 	github.com/lestrrat-go/httpcc v1.0.1 // indirect
 	github.com/lestrrat-go/httprc v1.0.4 // indirect
 	github.com/lestrrat-go/iter v1.0.2 // indirect
-	github.com/lestrrat-go/jwx/v2 v2.0.6 // indirect
 	github.com/lestrrat-go/option v1.0.0 // indirect
 	golang.org/x/sys v0.7.0 // indirect
 )
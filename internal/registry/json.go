@@ -0,0 +1,31 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Marshal encodes the key as a json string. Keys that are not registered
+// fail with the invalid error of their type.
+func Marshal[K ~string, V any](r *Registry[K, V], key K, invalid error) ([]byte, error) {
+	if !r.Contains(key) {
+		return nil, invalid
+	}
+	return json.Marshal(string(key))
+}
+
+// Unmarshal decodes a json string into key. Values that are not json
+// strings or not registered keys fail with the invalid error of their
+// type, and key is left untouched.
+func Unmarshal[K ~string, V any](r *Registry[K, V], data []byte, key *K, invalid error) error {
+	var s string
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		return fmt.Errorf("%w: %w", invalid, err)
+	}
+	if !r.Contains(K(s)) {
+		return invalid
+	}
+	*key = K(s)
+	return nil
+}
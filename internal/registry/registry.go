@@ -0,0 +1,93 @@
+// Package registry provides a concurrency-safe registry of protocol
+// values, such as the contents of the IANA registries of GNAP, that
+// distinguishes the values defined by the specifications from the
+// extension values registered at run time.
+//
+// Each registry backs an enumerated string type, whose values are valid
+// when registered and standard when defined by the specifications.
+// Extension values are registered so that they are accepted when
+// encoding and decoding with [Marshal] and [Unmarshal], and can be
+// unregistered again, unlike standard values.
+package registry
+
+import (
+	"errors"
+	"sync"
+)
+
+// Errors returned by the [Registry] methods.
+var (
+	ErrEmpty      = errors.New("empty registry value")
+	ErrRegistered = errors.New("value already registered")
+	ErrStandard   = errors.New("standard value cannot be unregistered")
+)
+
+// Registry maps the registered keys to their values. The zero value
+// is not usable, create registries with [New].
+type Registry[K comparable, V any] struct {
+	mu       sync.RWMutex
+	values   map[K]V
+	standard map[K]bool
+}
+
+// New creates a registry holding the standard values.
+func New[K comparable, V any](standard map[K]V) *Registry[K, V] {
+	r := &Registry[K, V]{
+		values:   make(map[K]V, len(standard)),
+		standard: make(map[K]bool, len(standard)),
+	}
+	for k, v := range standard {
+		r.values[k] = v
+		r.standard[k] = true
+	}
+	return r
+}
+
+// Lookup returns the value registered for the key.
+func (r *Registry[K, V]) Lookup(key K) (V, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	v, ok := r.values[key]
+	return v, ok
+}
+
+// Contains reports whether the key is registered.
+func (r *Registry[K, V]) Contains(key K) bool {
+	_, ok := r.Lookup(key)
+	return ok
+}
+
+// Standard reports whether the key is one of the standard values.
+func (r *Registry[K, V]) Standard(key K) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.standard[key]
+}
+
+// Register adds an extension value. It returns [ErrRegistered] if the
+// key is already registered, standard or not.
+func (r *Registry[K, V]) Register(key K, value V) error {
+	var zero K
+	if key == zero {
+		return ErrEmpty
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.values[key]; ok {
+		return ErrRegistered
+	}
+	r.values[key] = value
+	return nil
+}
+
+// Unregister removes an extension value. Unregistering a key that is
+// not registered is a no-op, while standard values cannot be removed.
+func (r *Registry[K, V]) Unregister(key K) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.standard[key] {
+		return ErrStandard
+	}
+	delete(r.values, key)
+	return nil
+}
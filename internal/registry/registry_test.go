@@ -0,0 +1,95 @@
+package registry
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	r := New(map[string]int{"std": 1})
+	if !r.Contains("std") || !r.Standard("std") {
+		t.Errorf("Registry does not hold the standard value")
+	}
+	if err := r.Register("std", 2); !errors.Is(err, ErrRegistered) {
+		t.Errorf("Registry.Register() error = %v, want %v", err, ErrRegistered)
+	}
+	if err := r.Register("", 2); !errors.Is(err, ErrEmpty) {
+		t.Errorf("Registry.Register() error = %v, want %v", err, ErrEmpty)
+	}
+	if err := r.Register("ext", 2); err != nil {
+		t.Fatalf("Registry.Register() error = %v", err)
+	}
+	if v, ok := r.Lookup("ext"); !ok || v != 2 {
+		t.Errorf("Registry.Lookup() = %v, %v, want 2, true", v, ok)
+	}
+	if r.Standard("ext") {
+		t.Errorf("Registry.Standard() = true for extension value")
+	}
+	if err := r.Unregister("std"); !errors.Is(err, ErrStandard) {
+		t.Errorf("Registry.Unregister() error = %v, want %v", err, ErrStandard)
+	}
+	if err := r.Unregister("ext"); err != nil {
+		t.Errorf("Registry.Unregister() error = %v", err)
+	}
+	if r.Contains("ext") {
+		t.Errorf("Registry.Contains() = true after Unregister")
+	}
+}
+
+func TestRegistry_concurrent(t *testing.T) {
+	r := New(map[int]bool{})
+	var wg sync.WaitGroup
+	for i := 1; i <= 50; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			_ = r.Register(i, true)
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			_ = r.Contains(i)
+		}(i)
+	}
+	wg.Wait()
+	for i := 1; i <= 50; i++ {
+		if !r.Contains(i) {
+			t.Errorf("Registry.Contains(%d) = false", i)
+		}
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	type mode string
+	invalid := errors.New("invalid mode")
+	r := New(map[mode]struct{}{"redirect": {}})
+	tests := []struct {
+		name    string
+		data    string
+		want    mode
+		wantErr error
+	}{
+		{name: "registered", data: `"redirect"`, want: "redirect"},
+		{name: "unregistered", data: `"push"`, wantErr: invalid},
+		{name: "not a string", data: `42`, wantErr: invalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got mode
+			err := Unmarshal(r, []byte(tt.data), &got, invalid)
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Fatalf("Unmarshal() = %q, %v, want %q, %v", got, err, tt.want, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			data, err := Marshal(r, got, invalid)
+			if err != nil || string(data) != tt.data {
+				t.Errorf("Marshal() = %s, %v, want %s", data, err, tt.data)
+			}
+		})
+	}
+	if _, err := Marshal(r, "push", invalid); !errors.Is(err, invalid) {
+		t.Errorf("Marshal() error = %v, want %v", err, invalid)
+	}
+}
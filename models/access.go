@@ -3,6 +3,7 @@ package models
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 )
 
 // ErrInvalidAccessRight is returned when an access right object
@@ -20,13 +21,14 @@ var ErrInvalidTokenResponse = errors.New("invalid token response")
 // AccessRight represents the rights and privileges requested
 // or granted during a gnap request flow.
 type AccessRight struct {
-	Type       string   `json:"type"`
-	Actions    []string `json:"actions,omitempty"`
-	Locations  []string `json:"locations,omitempty"`
-	Datatypes  []string `json:"datatypes,omitempty"`
-	Identifier string   `json:"identifier,omitempty"`
-	Privileges []string `json:"privileges,omitempty"`
-	Ref        string   `json:"-"`
+	Type       string     `json:"type"`
+	Actions    []string   `json:"actions,omitempty"`
+	Locations  []string   `json:"locations,omitempty"`
+	Datatypes  []string   `json:"datatypes,omitempty"`
+	Identifier string     `json:"identifier,omitempty"`
+	Privileges []string   `json:"privileges,omitempty"`
+	Ref        string     `json:"-"`
+	Extensions Extensions `json:"-"`
 }
 
 // MarshalJSON implements the [json.Marshaler] interface.
@@ -35,10 +37,13 @@ func (r AccessRight) MarshalJSON() ([]byte, error) {
 		return json.Marshal(r.Ref)
 	}
 	type Alias AccessRight
-	return json.Marshal(Alias(r))
+	return marshalExtensions(Alias(r), r.Extensions)
 }
 
-// UnmarshalJSON implements the [json.Unmarshaler] interface.
+// UnmarshalJSON implements the [json.Unmarshaler] interface. The
+// API-specific members of access rights by value are kept in Extensions.
+// Decoding is structural only: the access right is not checked against
+// its [AccessSchema], which is done by [ValidateAccess].
 func (r *AccessRight) UnmarshalJSON(data []byte) error {
 	var ref string
 	err := json.Unmarshal(data, &ref)
@@ -48,12 +53,12 @@ func (r *AccessRight) UnmarshalJSON(data []byte) error {
 	}
 	type Alias AccessRight
 	var alias Alias
-	err = json.Unmarshal(data, &alias)
-	if err == nil {
-		*r = AccessRight(alias)
-		return nil
+	alias.Extensions, err = unmarshalExtensions(data, &alias)
+	if err != nil {
+		return ErrInvalidAccessRight
 	}
-	return ErrInvalidAccessRight
+	*r = AccessRight(alias)
+	return nil
 }
 
 // ATRequest is a wrapper aroung TokenRequest for
@@ -119,3 +124,60 @@ func (req *ATResponse) UnmarshalJSON(data []byte) error {
 	}
 	return ErrInvalidTokenResponse
 }
+
+// Validate checks the requested access tokens. Multiple access token
+// requests must be labeled with unique labels.
+func (req ATRequest) Validate() error {
+	if req.Multiple == nil {
+		return req.Single.Validate()
+	}
+	if len(req.Multiple) == 0 {
+		return ErrMissingField
+	}
+	labels := make(map[string]struct{})
+	for i, token := range req.Multiple {
+		err := validateLabel(labels, token.Label, ErrInvalidTokenRequest)
+		if err == nil {
+			err = token.Validate()
+		}
+		if err != nil {
+			return fieldError(index(i), err)
+		}
+	}
+	return nil
+}
+
+// Validate checks the granted access tokens. Multiple access tokens
+// must be labeled with unique labels.
+func (res ATResponse) Validate() error {
+	if res.Multiple == nil {
+		return res.Single.Validate()
+	}
+	if len(res.Multiple) == 0 {
+		return ErrMissingField
+	}
+	labels := make(map[string]struct{})
+	for i, token := range res.Multiple {
+		err := validateLabel(labels, token.Label, ErrInvalidTokenResponse)
+		if err == nil {
+			err = token.Validate()
+		}
+		if err != nil {
+			return fieldError(index(i), err)
+		}
+	}
+	return nil
+}
+
+// validateLabel checks that label is present and not in the set of
+// labels seen before, then adds it to the set.
+func validateLabel(seen map[string]struct{}, label string, kind error) error {
+	if label == "" {
+		return fieldError("label", fmt.Errorf("%w: %w", ErrMissingField, kind))
+	}
+	if _, ok := seen[label]; ok {
+		return fieldError("label", fmt.Errorf("duplicate label: %w", kind))
+	}
+	seen[label] = struct{}{}
+	return nil
+}
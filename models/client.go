@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 
+	"github.com/bingxueshuang/gnap/internal/registry"
 	"github.com/yaronf/httpsign"
 )
 
@@ -36,39 +37,44 @@ const (
 	ED25519           HTTPSigAlg = "ed25519"
 )
 
-// sigAlgRegistry is a quick mapping from string values
-// to valid http signature algorithms.
-var sigAlgRegistry = map[string]HTTPSigAlg{
-	"rsa-pss-sha512":    RSA_PSS_SHA512,
-	"rsa-v1_5-sha256":   RSA_SHA256,
-	"hmac-sha256":       HMAC_SHA256,
-	"ecdsa-p256-sha256": ECDSA_P256_SHA256,
-	"ecdsa-p384-sha384": ECDSA_P384_SHA384,
-	"ed25519":           ED25519,
+// sigAlgs is the registry of http signature algorithms.
+var sigAlgs = registry.New(map[HTTPSigAlg]struct{}{
+	RSA_PSS_SHA512:    {},
+	RSA_SHA256:        {},
+	HMAC_SHA256:       {},
+	ECDSA_P256_SHA256: {},
+	ECDSA_P384_SHA384: {},
+	ED25519:           {},
+})
+
+// RegisterHTTPSigAlg registers an extension http signature algorithm.
+func RegisterHTTPSigAlg(alg HTTPSigAlg) error {
+	return sigAlgs.Register(alg, struct{}{})
+}
+
+// UnregisterHTTPSigAlg unregisters an extension http signature algorithm.
+func UnregisterHTTPSigAlg(alg HTTPSigAlg) error {
+	return sigAlgs.Unregister(alg)
+}
+
+// Valid reports whether the http signature algorithm is registered.
+func (alg HTTPSigAlg) Valid() bool {
+	return sigAlgs.Contains(alg)
+}
+
+// Standard reports whether the http signature algorithm is a standard value.
+func (alg HTTPSigAlg) Standard() bool {
+	return sigAlgs.Standard(alg)
 }
 
 // MarshalJSON implements the [json.Marshaler] interface.
 func (alg HTTPSigAlg) MarshalJSON() ([]byte, error) {
-	_, ok := sigAlgRegistry[string(alg)]
-	if ok {
-		return json.Marshal(string(alg))
-	}
-	return nil, ErrInvalidSigAlg
+	return registry.Marshal(sigAlgs, alg, ErrInvalidSigAlg)
 }
 
 // UnmarshalJSON implements the [json.Unmarshaler] interface.
 func (alg *HTTPSigAlg) UnmarshalJSON(data []byte) error {
-	var sigalg string
-	err := json.Unmarshal(data, &sigalg)
-	if err != nil {
-		return err
-	}
-	_, ok := sigAlgRegistry[sigalg]
-	if ok {
-		*alg = HTTPSigAlg(sigalg)
-		return nil
-	}
-	return ErrInvalidSigAlg
+	return registry.Unmarshal(sigAlgs, data, alg, ErrInvalidSigAlg)
 }
 
 // DigestAlg represents HTTP Content Digest algorithm.
@@ -80,35 +86,40 @@ const (
 	DigestSha512 DigestAlg = httpsign.DigestSha512
 )
 
-// digestAlgRegistry is a quick mapping from string
-// values to valid digest algorithms.
-var digestAlgRegistry = map[string]DigestAlg{
-	httpsign.DigestSha256: DigestSha256,
-	httpsign.DigestSha512: DigestSha512,
+// digestAlgs is the registry of content digest algorithms.
+var digestAlgs = registry.New(map[DigestAlg]struct{}{
+	DigestSha256: {},
+	DigestSha512: {},
+})
+
+// RegisterDigestAlg registers an extension content digest algorithm.
+func RegisterDigestAlg(alg DigestAlg) error {
+	return digestAlgs.Register(alg, struct{}{})
+}
+
+// UnregisterDigestAlg unregisters an extension content digest algorithm.
+func UnregisterDigestAlg(alg DigestAlg) error {
+	return digestAlgs.Unregister(alg)
+}
+
+// Valid reports whether the content digest algorithm is registered.
+func (alg DigestAlg) Valid() bool {
+	return digestAlgs.Contains(alg)
+}
+
+// Standard reports whether the content digest algorithm is a standard value.
+func (alg DigestAlg) Standard() bool {
+	return digestAlgs.Standard(alg)
 }
 
 // MarshalJSON implements the [json.Marshaler] interface.
 func (alg DigestAlg) MarshalJSON() ([]byte, error) {
-	_, ok := digestAlgRegistry[string(alg)]
-	if ok {
-		return json.Marshal(string(alg))
-	}
-	return nil, ErrInvalidDigestAlg
+	return registry.Marshal(digestAlgs, alg, ErrInvalidDigestAlg)
 }
 
-// UnmarshalJSON implements [json.Unmarshaler] interface.
+// UnmarshalJSON implements the [json.Unmarshaler] interface.
 func (alg *DigestAlg) UnmarshalJSON(data []byte) error {
-	var digalg string
-	err := json.Unmarshal(data, &digalg)
-	if err != nil {
-		return err
-	}
-	_, ok := digestAlgRegistry[digalg]
-	if ok {
-		*alg = DigestAlg(digalg)
-		return nil
-	}
-	return ErrInvalidDigestAlg
+	return registry.Unmarshal(digestAlgs, data, alg, ErrInvalidDigestAlg)
 }
 
 // ProofMethod represents the proofing method for
@@ -123,37 +134,42 @@ const (
 	ProofJWS     ProofMethod = "jws"
 )
 
-// proofRegistry is a mapping from valid string values to
-// corresponding proof methods.
-var proofRegistry = map[string]ProofMethod{
-	"httpsig": ProofHTTPSig,
-	"mtls":    ProofMTLS,
-	"jwsd":    ProofJWSD,
-	"jws":     ProofJWS,
+// proofMethods is the registry of proof methods.
+var proofMethods = registry.New(map[ProofMethod]struct{}{
+	ProofHTTPSig: {},
+	ProofMTLS:    {},
+	ProofJWSD:    {},
+	ProofJWS:     {},
+})
+
+// RegisterProofMethod registers an extension proof method.
+func RegisterProofMethod(method ProofMethod) error {
+	return proofMethods.Register(method, struct{}{})
+}
+
+// UnregisterProofMethod unregisters an extension proof method.
+func UnregisterProofMethod(method ProofMethod) error {
+	return proofMethods.Unregister(method)
+}
+
+// Valid reports whether the proof method is registered.
+func (p ProofMethod) Valid() bool {
+	return proofMethods.Contains(p)
+}
+
+// Standard reports whether the proof method is a standard value.
+func (p ProofMethod) Standard() bool {
+	return proofMethods.Standard(p)
 }
 
 // MarshalJSON implements the [json.Marshaler] interface.
 func (p ProofMethod) MarshalJSON() ([]byte, error) {
-	_, ok := proofRegistry[string(p)]
-	if ok {
-		return json.Marshal(string(p))
-	}
-	return nil, ErrInvalidProofMethod
+	return registry.Marshal(proofMethods, p, ErrInvalidProofMethod)
 }
 
 // UnmarshalJSON implements the [json.Unmarshaler] interface.
 func (p *ProofMethod) UnmarshalJSON(data []byte) error {
-	var proof string
-	err := json.Unmarshal(data, &proof)
-	if err != nil {
-		return err
-	}
-	_, ok := proofRegistry[string(proof)]
-	if ok {
-		*p = ProofMethod(proof)
-		return nil
-	}
-	return ErrInvalidProofMethod
+	return registry.Unmarshal(proofMethods, data, p, ErrInvalidProofMethod)
 }
 
 // Proof implements the [Proof] interface.
@@ -171,45 +187,81 @@ const (
 	FormatCertS256 KeyFormat = "cert#S256"
 )
 
-// keyFormatRegistry is a simple mapping from valid string values
-// to the corresponding key format.
-var keyFormatRegistry = map[string]KeyFormat{
-	"jwk":       FormatJWK,
-	"cert":      FormatCert,
-	"cert#S256": FormatCertS256,
+// keyFormats is the registry of key formats.
+var keyFormats = registry.New(map[KeyFormat]struct{}{
+	FormatJWK:      {},
+	FormatCert:     {},
+	FormatCertS256: {},
+})
+
+// RegisterKeyFormat registers an extension key format.
+func RegisterKeyFormat(format KeyFormat) error {
+	return keyFormats.Register(format, struct{}{})
+}
+
+// UnregisterKeyFormat unregisters an extension key format.
+func UnregisterKeyFormat(format KeyFormat) error {
+	return keyFormats.Unregister(format)
 }
 
-// MarshalJSON implements the [json.MarshalJSON] interface.
+// Valid reports whether the key format is registered.
+func (kf KeyFormat) Valid() bool {
+	return keyFormats.Contains(kf)
+}
+
+// Standard reports whether the key format is a standard value.
+func (kf KeyFormat) Standard() bool {
+	return keyFormats.Standard(kf)
+}
+
+// MarshalJSON implements the [json.Marshaler] interface.
 func (kf KeyFormat) MarshalJSON() ([]byte, error) {
-	_, ok := keyFormatRegistry[string(kf)]
-	if ok {
-		return json.Marshal(string(kf))
-	}
-	return nil, ErrInvalidKeyFormat
+	return registry.Marshal(keyFormats, kf, ErrInvalidKeyFormat)
 }
 
-// UnmarshalJSON implements [json.Unmarshaler] interface.
+// UnmarshalJSON implements the [json.Unmarshaler] interface.
 func (kf *KeyFormat) UnmarshalJSON(data []byte) error {
-	var format string
-	err := json.Unmarshal(data, &format)
-	if err != nil {
-		return err
-	}
-	_, ok := keyFormatRegistry[string(format)]
-	if ok {
-		*kf = KeyFormat(format)
-		return nil
-	}
-	return ErrInvalidKeyFormat
+	return registry.Unmarshal(keyFormats, data, kf, ErrInvalidKeyFormat)
 }
 
 // ClientInstance defines a client by reference (string) or
 // by value (object).
 type ClientInstance struct {
-	Key     ClientKey     `json:"key"`
-	ClassID string        `json:"class_id,omitempty"`
-	Display ClientDisplay `json:"display,omitempty"`
-	Ref     string
+	Key        ClientKey      `json:"key"`
+	ClassID    string         `json:"class_id,omitempty"`
+	Display    *ClientDisplay `json:"display,omitempty"`
+	Ref        string         `json:"-"`
+	Extensions Extensions     `json:"-"`
+}
+
+// MarshalJSON implements the [json.Marshaler] interface. Encodes to
+// json string for client by reference, json object otherwise.
+func (c ClientInstance) MarshalJSON() ([]byte, error) {
+	if c.Ref != "" {
+		return json.Marshal(c.Ref)
+	}
+	type Alias ClientInstance
+	return marshalExtensions(Alias(c), c.Extensions)
+}
+
+// UnmarshalJSON implements the [json.Unmarshaler] interface. Decodes
+// from a client reference string or a client object, keeping unknown
+// members of the object in Extensions.
+func (c *ClientInstance) UnmarshalJSON(data []byte) error {
+	var ref string
+	err := json.Unmarshal(data, &ref)
+	if err == nil { // client by reference
+		*c = ClientInstance{Ref: ref}
+		return nil
+	}
+	type Alias ClientInstance
+	var alias Alias
+	alias.Extensions, err = unmarshalExtensions(data, &alias)
+	if err != nil {
+		return err
+	}
+	*c = ClientInstance(alias)
+	return nil
 }
 
 // NewClient is the constructor for client instance by value (object).
@@ -231,8 +283,8 @@ type clientOption func(c *ClientInstance) error
 // instance for displaying to the user.
 type ClientDisplay struct {
 	Name string `json:"name"`
-	URI  URL    `json:"uri,omitempty"`
-	Logo URL    `json:"logo_uri,omitempty"`
+	URI  *URL   `json:"uri,omitempty"`
+	Logo *URL   `json:"logo_uri,omitempty"`
 }
 
 // ClientKey the key object of the client. It is used as
@@ -247,6 +299,69 @@ type ClientKey struct {
 
 // TODO: constructor for ClientKey to facilitate with std.
 
+// MarshalJSON implements the [json.Marshaler] interface. Encodes to
+// json string for key by reference, json object otherwise.
+func (k ClientKey) MarshalJSON() ([]byte, error) {
+	if k.Ref != "" {
+		return json.Marshal(k.Ref)
+	}
+	type Alias ClientKey
+	return json.Marshal(Alias(k))
+}
+
+// UnmarshalJSON implements the [json.Unmarshaler] interface. Decodes
+// from a key reference string or a key object.
+func (k *ClientKey) UnmarshalJSON(data []byte) error {
+	var ref string
+	err := json.Unmarshal(data, &ref)
+	if err == nil { // key by reference
+		*k = ClientKey{Ref: ref}
+		return nil
+	}
+	type Alias ClientKey
+	var alias struct {
+		Alias
+		Proof json.RawMessage `json:"proof"`
+	}
+	err = json.Unmarshal(data, &alias)
+	if err != nil {
+		return err
+	}
+	proof, err := decodeProof(alias.Proof)
+	if err != nil {
+		return err
+	}
+	*k = ClientKey(alias.Alias)
+	k.Proof = proof
+	return nil
+}
+
+// decodeProof decodes the proof method given either as a string or as
+// an object with method property.
+func decodeProof(data []byte) (Proofer, error) {
+	var method ProofMethod
+	err := json.Unmarshal(data, &method)
+	if err == nil { // proof method by reference
+		return method, nil
+	}
+	var obj struct {
+		Method ProofMethod `json:"method"`
+	}
+	err = json.Unmarshal(data, &obj)
+	if err != nil {
+		return nil, ErrInvalidProofMethod
+	}
+	if obj.Method != ProofHTTPSig {
+		return obj.Method, nil
+	}
+	var sig HTTPSig
+	err = json.Unmarshal(data, &sig)
+	if err != nil {
+		return nil, err
+	}
+	return sig, nil
+}
+
 // Proofer describes any object that conveys the proofing information.
 type Proofer interface {
 	Proof() ProofMethod
@@ -256,10 +371,119 @@ type Proofer interface {
 type HTTPSig struct {
 	Method    ProofMethod `json:"method"` // == "httpsig"
 	SigAlg    HTTPSigAlg  `json:"alg"`
-	DigestAlg DigestAlg   `json:"content-digest"`
+	DigestAlg DigestAlg   `json:"content-digest-alg,omitempty"`
+}
+
+// MarshalJSON implements the [json.Marshaler] interface. The method
+// is always encoded as "httpsig".
+func (sig HTTPSig) MarshalJSON() ([]byte, error) {
+	type Alias HTTPSig
+	sig.Method = ProofHTTPSig
+	return json.Marshal(Alias(sig))
+}
+
+// UnmarshalJSON implements the [json.Unmarshaler] interface. For
+// compatibility with draft-13 peers, the digest algorithm is also
+// accepted under the "content-digest" member.
+func (sig *HTTPSig) UnmarshalJSON(data []byte) error {
+	type Alias HTTPSig
+	var alias struct {
+		Alias
+		Draft DigestAlg `json:"content-digest,omitempty"`
+	}
+	err := json.Unmarshal(data, &alias)
+	if err != nil {
+		return err
+	}
+	*sig = HTTPSig(alias.Alias)
+	if sig.DigestAlg == "" {
+		sig.DigestAlg = alias.Draft
+	}
+	return nil
 }
 
 // Proof implements [Proofer] interface.
 func (sig HTTPSig) Proof() ProofMethod {
 	return ProofHTTPSig
 }
+
+// Validate checks the client instance. A client by value must present
+// a valid key.
+func (c ClientInstance) Validate() error {
+	if c.Ref != "" {
+		if c.Key.Proof != nil || c.Key.Ref != "" || c.ClassID != "" {
+			return ErrConflictingFields
+		}
+		return nil
+	}
+	err := c.Key.Validate()
+	if err != nil {
+		return fieldError("key", err)
+	}
+	if c.Display == nil {
+		return nil
+	}
+	if c.Display.URI != nil {
+		err = validateURL(c.Display.URI)
+		if err != nil {
+			return fieldError("display.uri", err)
+		}
+	}
+	if c.Display.Logo != nil {
+		err = validateURL(c.Display.Logo)
+		if err != nil {
+			return fieldError("display.logo_uri", err)
+		}
+	}
+	return nil
+}
+
+// Validate checks the key. A key by value must declare the proof method
+// and carry exactly one form of the public key.
+func (k ClientKey) Validate() error {
+	if k.Ref != "" {
+		if k.Proof != nil {
+			return ErrConflictingFields
+		}
+		return nil
+	}
+	if k.Proof == nil {
+		return fieldError("proof", ErrMissingField)
+	}
+	if !proofMethods.Contains(k.Proof.Proof()) {
+		return fieldError("proof", ErrInvalidProofMethod)
+	}
+	if sig, ok := k.Proof.(HTTPSig); ok {
+		err := sig.Validate()
+		if err != nil {
+			return fieldError("proof", err)
+		}
+	}
+	forms := 0
+	for _, present := range []bool{len(k.JWK) != 0, k.Cert != "", k.CertS256 != ""} {
+		if present {
+			forms++
+		}
+	}
+	switch {
+	case forms == 0:
+		return fieldError("jwk", ErrMissingField)
+	case forms > 1:
+		return ErrConflictingFields
+	}
+	return nil
+}
+
+// Validate checks the parameters of the http signature proof method.
+func (sig HTTPSig) Validate() error {
+	if !sigAlgs.Contains(sig.SigAlg) {
+		return fieldError("alg", ErrInvalidSigAlg)
+	}
+	if sig.DigestAlg == "" {
+		return nil
+	}
+	if !digestAlgs.Contains(sig.DigestAlg) {
+		return fieldError("content-digest-alg", ErrInvalidDigestAlg)
+	}
+	return nil
+}
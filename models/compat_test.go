@@ -0,0 +1,108 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestDraft13 decodes messages of draft-13 peers, which differ from
+// RFC 9635 in the encoding of some members.
+func TestDraft13(t *testing.T) {
+	tests := []struct {
+		name  string
+		model func() any
+		check func(t *testing.T, model any)
+		// roundTrip is set when the draft-13 encoding is preserved.
+		roundTrip bool
+	}{
+		{
+			name:  "grant-request-user-code.json",
+			model: func() any { return new(GrantRequest) },
+			check: func(t *testing.T, model any) {
+				sig, ok := model.(*GrantRequest).Client.Key.Proof.(HTTPSig)
+				if !ok || sig.DigestAlg != DigestSha256 {
+					t.Errorf("HTTPSig.DigestAlg = %v, want %v", sig.DigestAlg, DigestSha256)
+				}
+			},
+		},
+		{
+			name:  "key-httpsig.json",
+			model: func() any { return new(ClientKey) },
+			check: func(t *testing.T, model any) {
+				sig, ok := model.(*ClientKey).Proof.(HTTPSig)
+				if !ok || sig.DigestAlg != DigestSha512 {
+					t.Errorf("HTTPSig.DigestAlg = %v, want %v", sig.DigestAlg, DigestSha512)
+				}
+			},
+		},
+		{
+			name:  "grant-response-multiple.json",
+			model: func() any { return new(GrantResponse) },
+			check: func(t *testing.T, model any) {
+				checkManage(t, model.(*GrantResponse).AccessToken.Multiple[1].Manage)
+			},
+			roundTrip: true,
+		},
+		{
+			name:  "grant-response-token.json",
+			model: func() any { return new(GrantResponse) },
+			check: func(t *testing.T, model any) {
+				checkManage(t, model.(*GrantResponse).AccessToken.Single.Manage)
+			},
+			roundTrip: true,
+		},
+		{
+			name:  "token-response.json",
+			model: func() any { return new(TokenResponse) },
+			check: func(t *testing.T, model any) {
+				checkManage(t, model.(*TokenResponse).Manage)
+			},
+			roundTrip: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", "draft13", tt.name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			model := tt.model()
+			err = json.Unmarshal(data, model)
+			if err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			tt.check(t, model)
+			if !tt.roundTrip {
+				return
+			}
+			var want bytes.Buffer
+			_ = json.Compact(&want, data)
+			got, err := json.Marshal(model)
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+			if !bytes.Equal(got, want.Bytes()) {
+				t.Errorf("json.Marshal() =\n%s\nwant\n%s", got, want.Bytes())
+			}
+		})
+	}
+}
+
+// checkManage expects the draft-13 management URI of the examples,
+// without a management access token.
+func checkManage(t *testing.T, m *TokenManage) {
+	t.Helper()
+	const want = "https://server.example.com/token/PRY5NM33OMUKMKSKU"
+	if m == nil {
+		t.Fatal("TokenResponse.Manage = nil")
+	}
+	if m.URI.String() != want {
+		t.Errorf("TokenManage.URI = %v, want %v", m.URI.String(), want)
+	}
+	if m.Token.Value != "" {
+		t.Errorf("TokenManage.Token = %v, want empty", m.Token)
+	}
+}
@@ -0,0 +1,127 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// specDir holds the examples of RFC 9635 and of the GNAP resource server
+// specification, the latter prefixed with "rs-", as they appear in the
+// specifications. Key material elided by the specifications is filled
+// in with the complete key given elsewhere in them.
+const specDir = "testdata/spec"
+
+// corpus maps every example in specDir to the model it decodes into.
+// Examples with unordered set have their members in another order than
+// they are encoded by this package.
+var corpus = map[string]struct {
+	model     func() any
+	unordered bool
+}{
+	"access-right.json":                    {model: func() any { return new(AccessRight) }},
+	"access-rights-mixed.json":             {model: func() any { return new([]AccessRight) }},
+	"access-rights-privileges.json":        {model: func() any { return new([]AccessRight) }},
+	"client-instance.json":                 {model: func() any { return new(ClientInstance) }},
+	"client-ref.json":                      {model: func() any { return new(ClientInstance) }},
+	"continue-request.json":                {model: func() any { return new(ContinueRequest) }},
+	"continue-response.json":               {model: func() any { return new(ContinueResponse) }, unordered: true},
+	"discovery.json":                       {model: func() any { return new(Discovery) }},
+	"error-response.json":                  {model: func() any { return new(GrantResponse) }},
+	"grant-request.json":                   {model: func() any { return new(GrantRequest) }, unordered: true},
+	"grant-request-cert.json":              {model: func() any { return new(GrantRequest) }},
+	"grant-request-multiple.json":          {model: func() any { return new(GrantRequest) }},
+	"grant-request-ref.json":               {model: func() any { return new(GrantRequest) }},
+	"grant-request-user.json":              {model: func() any { return new(GrantRequest) }},
+	"grant-request-user-code.json":         {model: func() any { return new(GrantRequest) }},
+	"grant-response.json":                  {model: func() any { return new(GrantResponse) }, unordered: true},
+	"grant-response-multiple.json":         {model: func() any { return new(GrantResponse) }},
+	"grant-response-token.json":            {model: func() any { return new(GrantResponse) }, unordered: true},
+	"grant-response-user-code.json":        {model: func() any { return new(GrantResponse) }},
+	"interact-callback.json":               {model: func() any { return new(IACallback) }},
+	"interact-request.json":                {model: func() any { return new(IARequest) }},
+	"interact-request-all.json":            {model: func() any { return new(IARequest) }},
+	"interact-request-code.json":           {model: func() any { return new(IARequest) }},
+	"interact-response.json":               {model: func() any { return new(IAResponse) }},
+	"interact-response-all.json":           {model: func() any { return new(IAResponse) }},
+	"key-cert.json":                        {model: func() any { return new(ClientKey) }},
+	"key-httpsig.json":                     {model: func() any { return new(ClientKey) }},
+	"key-ref.json":                         {model: func() any { return new(ClientKey) }},
+	"subject-request.json":                 {model: func() any { return new(SubRequest) }},
+	"subject-response.json":                {model: func() any { return new(SubResponse) }, unordered: true},
+	"token-request.json":                   {model: func() any { return new(TokenRequest) }},
+	"token-request-flags.json":             {model: func() any { return new(TokenRequest) }},
+	"token-request-multiple.json":          {model: func() any { return new(ATRequest) }, unordered: true},
+	"token-response.json":                  {model: func() any { return new(TokenResponse) }},
+	"token-response-bearer.json":           {model: func() any { return new(TokenResponse) }},
+	"token-response-key.json":              {model: func() any { return new(TokenResponse) }},
+	"token-response-multiple.json":         {model: func() any { return new(ATResponse) }, unordered: true},
+	"user.json":                            {model: func() any { return new(EndUser) }, unordered: true},
+	"user-ref.json":                        {model: func() any { return new(EndUser) }},
+	"rs-grant-request-derived.json":        {model: func() any { return new(GrantRequest) }},
+	"rs-introspect-request.json":           {model: func() any { return new(IntrospectRequest) }},
+	"rs-introspect-request-access.json":    {model: func() any { return new(IntrospectRequest) }},
+	"rs-introspect-response.json":          {model: func() any { return new(IntrospectResponse) }},
+	"rs-introspect-response-inactive.json": {model: func() any { return new(IntrospectResponse) }},
+}
+
+// TestConformance decodes every example of the specifications into its
+// model, validates it where the model supports validation, and expects
+// the re-encoded message to be semantically equal to the example, and
+// byte-equivalent to the compacted example unless it is unordered.
+func TestConformance(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join(specDir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != len(corpus) {
+		t.Errorf("found %d examples, want %d", len(files), len(corpus))
+	}
+	for _, file := range files {
+		name := filepath.Base(file)
+		t.Run(name, func(t *testing.T) {
+			example, ok := corpus[name]
+			if !ok {
+				t.Fatalf("no model for example %s", name)
+			}
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			model := example.model()
+			err = json.Unmarshal(data, model)
+			if err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			if v, ok := reflect.ValueOf(model).Elem().Interface().(interface{ Validate() error }); ok {
+				if err := v.Validate(); err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+			}
+			got, err := json.Marshal(model)
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+			var want, have any
+			_ = json.Unmarshal(data, &want)
+			_ = json.Unmarshal(got, &have)
+			if !reflect.DeepEqual(have, want) {
+				t.Errorf("json.Marshal() =\n%s\nwant semantically equal to\n%s", got, data)
+			}
+			if example.unordered {
+				return
+			}
+			var compact bytes.Buffer
+			err = json.Compact(&compact, data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, compact.Bytes()) {
+				t.Errorf("json.Marshal() =\n%s\nwant\n%s", got, compact.Bytes())
+			}
+		})
+	}
+}
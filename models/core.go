@@ -15,16 +15,60 @@ type Discovery struct {
 	SubFormats    []subject.Format  `json:"sub_id_formats_supported,omitempty"`
 	AFormats      []AssertionFormat `json:"assertion_formats_supported,omitempty"`
 	KeyRotation   bool              `json:"key_rotation_supported,omitempty"`
+	Extensions    Extensions        `json:"-"`
+}
+
+// MarshalJSON implements the [json.Marshaler] interface. Extension
+// members are encoded after the members of the model.
+func (d Discovery) MarshalJSON() ([]byte, error) {
+	type Alias Discovery
+	return marshalExtensions(Alias(d), d.Extensions)
+}
+
+// UnmarshalJSON implements the [json.Unmarshaler] interface. Unknown
+// members are kept in Extensions.
+func (d *Discovery) UnmarshalJSON(data []byte) error {
+	type Alias Discovery
+	var alias Alias
+	ext, err := unmarshalExtensions(data, &alias)
+	if err != nil {
+		return err
+	}
+	alias.Extensions = ext
+	*d = Discovery(alias)
+	return nil
 }
 
 // GrantRequest represents the grant request for initiation
 // of the gnap flow.
 type GrantRequest struct {
-	AccessToken ATRequest      `json:"access_token,omitempty"`
-	Subject     SubRequest     `json:"subject,omitempty"`
+	AccessToken *ATRequest     `json:"access_token,omitempty"`
+	Subject     *SubRequest    `json:"subject,omitempty"`
 	Client      ClientInstance `json:"client"`
-	User        EndUser        `json:"user,omitempty"`
-	Interact    IARequest      `json:"interact,omitempty"`
+	User        *EndUser       `json:"user,omitempty"`
+	Interact    *IARequest     `json:"interact,omitempty"`
+	Extensions  Extensions     `json:"-"`
+}
+
+// MarshalJSON implements the [json.Marshaler] interface. Extension
+// members are encoded after the members of the model.
+func (req GrantRequest) MarshalJSON() ([]byte, error) {
+	type Alias GrantRequest
+	return marshalExtensions(Alias(req), req.Extensions)
+}
+
+// UnmarshalJSON implements the [json.Unmarshaler] interface. Unknown
+// members are kept in Extensions.
+func (req *GrantRequest) UnmarshalJSON(data []byte) error {
+	type Alias GrantRequest
+	var alias Alias
+	ext, err := unmarshalExtensions(data, &alias)
+	if err != nil {
+		return err
+	}
+	alias.Extensions = ext
+	*req = GrantRequest(alias)
+	return nil
 }
 
 // NewRequest is constructor for [GrantRequest] with mandatory client
@@ -45,12 +89,34 @@ type requestOption func(*GrantRequest) error
 
 // GrantResponse represents the AS response to a grant request.
 type GrantResponse struct {
-	Continue    ContinueResponse `json:"continue,omitempty"`
-	AccessToken ATResponse       `json:"access_token,omitempty"`
-	Interact    IAResponse       `json:"interact,omitempty"`
-	Subject     SubResponse      `json:"subject,omitempty"`
-	InstanceID  string           `json:"instance_id,omitempty"`
-	Error       GNAPError        `json:"error,omitempty"`
+	Continue    *ContinueResponse `json:"continue,omitempty"`
+	AccessToken *ATResponse       `json:"access_token,omitempty"`
+	Interact    *IAResponse       `json:"interact,omitempty"`
+	Subject     *SubResponse      `json:"subject,omitempty"`
+	InstanceID  string            `json:"instance_id,omitempty"`
+	Error       *GNAPError        `json:"error,omitempty"`
+	Extensions  Extensions        `json:"-"`
+}
+
+// MarshalJSON implements the [json.Marshaler] interface. Extension
+// members are encoded after the members of the model.
+func (res GrantResponse) MarshalJSON() ([]byte, error) {
+	type Alias GrantResponse
+	return marshalExtensions(Alias(res), res.Extensions)
+}
+
+// UnmarshalJSON implements the [json.Unmarshaler] interface. Unknown
+// members are kept in Extensions.
+func (res *GrantResponse) UnmarshalJSON(data []byte) error {
+	type Alias GrantResponse
+	var alias Alias
+	ext, err := unmarshalExtensions(data, &alias)
+	if err != nil {
+		return err
+	}
+	alias.Extensions = ext
+	*res = GrantResponse(alias)
+	return nil
 }
 
 // NewRequest is constructor for [GrantResponse] with optional parameters.
@@ -71,14 +137,36 @@ type responseOption func(*GrantResponse) error
 // ContinueRequest represents the continuation request
 // sent by the client instance after successful interaction.
 type ContinueRequest struct {
-	InteractRef string `json:"interact_ref"`
+	InteractRef string     `json:"interact_ref"`
+	Extensions  Extensions `json:"-"`
+}
+
+// MarshalJSON implements the [json.Marshaler] interface. Extension
+// members are encoded after the members of the model.
+func (req ContinueRequest) MarshalJSON() ([]byte, error) {
+	type Alias ContinueRequest
+	return marshalExtensions(Alias(req), req.Extensions)
+}
+
+// UnmarshalJSON implements the [json.Unmarshaler] interface. Unknown
+// members are kept in Extensions.
+func (req *ContinueRequest) UnmarshalJSON(data []byte) error {
+	type Alias ContinueRequest
+	var alias Alias
+	ext, err := unmarshalExtensions(data, &alias)
+	if err != nil {
+		return err
+	}
+	alias.Extensions = ext
+	*req = ContinueRequest(alias)
+	return nil
 }
 
 // ContinueResponse represents the continuation object
 // returned by the AS during the gnap request flow.
 type ContinueResponse struct {
 	URI   URL           `json:"uri"`
-	Wait  int           `json:"wait"`
+	Wait  int           `json:"wait,omitempty"`
 	Token ContinueToken `json:"access_token"`
 }
 
@@ -86,7 +174,7 @@ type ContinueResponse struct {
 // to request for single access token.
 func SingleToken(token TokenRequest) requestOption {
 	return func(req *GrantRequest) error {
-		req.AccessToken = ATRequest{Single: token}
+		req.AccessToken = &ATRequest{Single: token}
 		return nil
 	}
 }
@@ -107,7 +195,7 @@ func MultiToken(tokens ...TokenRequest) requestOption {
 			}
 			set[label] = struct{}{}
 		}
-		req.AccessToken = ATRequest{Multiple: tokens}
+		req.AccessToken = &ATRequest{Multiple: tokens}
 		return nil
 	}
 }
@@ -116,7 +204,7 @@ func MultiToken(tokens ...TokenRequest) requestOption {
 // to request for subject information.
 func WithSubject(sub SubRequest) requestOption {
 	return func(req *GrantRequest) error {
-		req.Subject = sub
+		req.Subject = &sub
 		return nil
 	}
 }
@@ -125,7 +213,7 @@ func WithSubject(sub SubRequest) requestOption {
 // to identify the RO to the AS.
 func WithUser(user EndUser) requestOption {
 	return func(req *GrantRequest) error {
-		req.User = user
+		req.User = &user
 		return nil
 	}
 }
@@ -134,7 +222,7 @@ func WithUser(user EndUser) requestOption {
 // to convey mode of interaction.
 func WithInteract(ia IARequest) requestOption {
 	return func(req *GrantRequest) error {
-		req.Interact = ia
+		req.Interact = &ia
 		return nil
 	}
 }
@@ -143,13 +231,13 @@ func WithInteract(ia IARequest) requestOption {
 // to grant single access token.
 func WithSingleResponse(token TokenResponse) responseOption {
 	return func(res *GrantResponse) error {
-		res.AccessToken = ATResponse{Single: token}
+		res.AccessToken = &ATResponse{Single: token}
 		return nil
 	}
 }
 
-// WithSingleResponse is an optional parameter for [NewResponse]
-// to grant multiple access token.
+// WithMultiResponse is an optional parameter for [NewResponse]
+// to grant multiple access tokens.
 func WithMultiResponse(tokens ...TokenResponse) responseOption {
 	return func(res *GrantResponse) error {
 		set := make(map[string]struct{})
@@ -162,8 +250,9 @@ func WithMultiResponse(tokens ...TokenResponse) responseOption {
 			if ok { // duplicate
 				return fmt.Errorf("duplicate label: %w", ErrInvalidTokenResponse)
 			}
+			set[label] = struct{}{}
 		}
-		res.AccessToken = ATResponse{Multiple: tokens}
+		res.AccessToken = &ATResponse{Multiple: tokens}
 		return nil
 	}
 }
@@ -172,7 +261,7 @@ func WithMultiResponse(tokens ...TokenResponse) responseOption {
 // to convey subject information to the client.
 func WithSubjectResponse(sub SubResponse) responseOption {
 	return func(res *GrantResponse) error {
-		res.Subject = sub
+		res.Subject = &sub
 		return nil
 	}
 }
@@ -181,7 +270,7 @@ func WithSubjectResponse(sub SubResponse) responseOption {
 // to convey the means of flow continuation to the client.
 func WithContinue(con ContinueResponse) responseOption {
 	return func(res *GrantResponse) error {
-		res.Continue = con
+		res.Continue = &con
 		return nil
 	}
 }
@@ -190,7 +279,7 @@ func WithContinue(con ContinueResponse) responseOption {
 // to convey the interaction urls to the client.
 func WithInteractResponse(ia IAResponse) responseOption {
 	return func(res *GrantResponse) error {
-		res.Interact = ia
+		res.Interact = &ia
 		return nil
 	}
 }
@@ -199,7 +288,7 @@ func WithInteractResponse(ia IAResponse) responseOption {
 // to respond with an error to the client.
 func WithError(err GNAPError) responseOption {
 	return func(res *GrantResponse) error {
-		res.Error = err
+		res.Error = &err
 		return nil
 	}
 }
@@ -212,3 +301,125 @@ func WithInstanceID(id string) responseOption {
 		return nil
 	}
 }
+
+// Validate checks the discovery document.
+func (d Discovery) Validate() error {
+	err := validateURL(&d.GrantRequest)
+	if err != nil {
+		return fieldError("grant_request_endpoint", err)
+	}
+	for i, mode := range d.StartModes {
+		if !startModes.Contains(mode) {
+			return fieldError("interaction_start_modes_supported"+index(i), ErrInvalidStartMode)
+		}
+	}
+	for i, method := range d.FinishMethods {
+		if !finishMethods.Contains(method) {
+			return fieldError("interaction_finish_methods_supported"+index(i), ErrInvalidFinishMethod)
+		}
+	}
+	for i, proof := range d.KeyProofs {
+		if !proofMethods.Contains(proof) {
+			return fieldError("key_proofs_supported"+index(i), ErrInvalidProofMethod)
+		}
+	}
+	for i, format := range d.SubFormats {
+		if !format.Valid() {
+			return fieldError("sub_id_formats_supported"+index(i), subject.ErrInvalidFormat)
+		}
+	}
+	for i, format := range d.AFormats {
+		if !assertionFormats.Contains(format) {
+			return fieldError("assertion_formats_supported"+index(i), ErrInvalidAFormat)
+		}
+	}
+	return nil
+}
+
+// Validate checks the grant request against the rules of RFC 9635.
+func (req GrantRequest) Validate() error {
+	if req.AccessToken != nil {
+		err := req.AccessToken.Validate()
+		if err != nil {
+			return fieldError("access_token", err)
+		}
+	}
+	if req.Subject != nil {
+		err := req.Subject.Validate()
+		if err != nil {
+			return fieldError("subject", err)
+		}
+	}
+	err := req.Client.Validate()
+	if err != nil {
+		return fieldError("client", err)
+	}
+	if req.User != nil {
+		err = req.User.Validate()
+		if err != nil {
+			return fieldError("user", err)
+		}
+	}
+	if req.Interact != nil {
+		err = req.Interact.Validate()
+		if err != nil {
+			return fieldError("interact", err)
+		}
+	}
+	return nil
+}
+
+// Validate checks the grant response against the rules of RFC 9635.
+// An error response must not carry any grant.
+func (res GrantResponse) Validate() error {
+	if res.Error != nil {
+		if _, ok := lookupError(res.Error.Code); !ok {
+			return fieldError("error.code", ErrInvalidErrorCode)
+		}
+		if res.AccessToken != nil || res.Interact != nil || res.Subject != nil {
+			return fieldError("error", ErrConflictingFields)
+		}
+		return nil
+	}
+	if res.Continue == nil && res.AccessToken == nil && res.Interact == nil && res.Subject == nil {
+		return ErrEmptyResponse
+	}
+	if res.Continue != nil {
+		err := res.Continue.Validate()
+		if err != nil {
+			return fieldError("continue", err)
+		}
+	}
+	if res.AccessToken != nil {
+		err := res.AccessToken.Validate()
+		if err != nil {
+			return fieldError("access_token", err)
+		}
+	}
+	if res.Interact != nil {
+		err := res.Interact.Validate()
+		if err != nil {
+			return fieldError("interact", err)
+		}
+		// interaction can only be completed through continuation
+		if res.Continue == nil {
+			return fieldError("continue", ErrMissingField)
+		}
+	}
+	if res.Subject != nil {
+		return fieldError("subject", res.Subject.Validate())
+	}
+	return nil
+}
+
+// Validate checks the continuation information.
+func (con ContinueResponse) Validate() error {
+	err := validateURL(&con.URI)
+	if err != nil {
+		return fieldError("uri", err)
+	}
+	if con.Wait < 0 {
+		return fieldError("wait", ErrInvalidValue)
+	}
+	return fieldError("access_token", con.Token.Validate())
+}
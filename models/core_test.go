@@ -0,0 +1,38 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWithMultiResponse(t *testing.T) {
+	access := []AccessRight{{Ref: "dolphin-metadata"}}
+	tests := []struct {
+		name    string
+		tokens  []TokenResponse
+		wantErr error
+	}{
+		{
+			name:   "distinct labels",
+			tokens: []TokenResponse{{Value: "x", Label: "a", Access: access}, {Value: "y", Label: "b", Access: access}},
+		},
+		{
+			name:    "duplicate label",
+			tokens:  []TokenResponse{{Value: "x", Label: "a", Access: access}, {Value: "y", Label: "a", Access: access}},
+			wantErr: ErrInvalidTokenResponse,
+		},
+		{
+			name:    "missing label",
+			tokens:  []TokenResponse{{Value: "x", Label: "a", Access: access}, {Value: "y", Access: access}},
+			wantErr: ErrInvalidTokenResponse,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewResponse(WithMultiResponse(tt.tokens...))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("NewResponse() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
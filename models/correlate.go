@@ -0,0 +1,111 @@
+package models
+
+// Outcome classifies an access token of the grant response against the
+// access token request it answers.
+type Outcome int
+
+// Outcomes of access token requests.
+const (
+	// Granted means the token was issued with all the requested access.
+	Granted Outcome = iota
+	// Downscoped means the token was issued with less access than
+	// requested.
+	Downscoped
+	// Denied means no token was issued for the request.
+	Denied
+	// Unrequested means the token was issued without a matching request.
+	Unrequested
+)
+
+// String implements the [fmt.Stringer] interface.
+func (o Outcome) String() string {
+	switch o {
+	case Granted:
+		return "granted"
+	case Downscoped:
+		return "downscoped"
+	case Denied:
+		return "denied"
+	case Unrequested:
+		return "unrequested"
+	}
+	return "unknown"
+}
+
+// Correlation pairs a requested access token with the issued one.
+type Correlation struct {
+	Label    string
+	Request  *TokenRequest
+	Response *TokenResponse
+	Outcome  Outcome
+	Missing  []AccessRight // requested but not granted
+	Extra    []AccessRight // granted but not requested
+}
+
+// Correlate pairs each requested access token with the token issued for
+// it, by label. A single token request is paired with a single token
+// response regardless of labels. The result lists the requests in order,
+// followed by the issued tokens that match no request. Rights passed by
+// reference are compared as is; expand them beforehand to compare by
+// value.
+func Correlate(req ATRequest, res *ATResponse) []Correlation {
+	if req.Multiple == nil {
+		single := req.Single
+		if res == nil {
+			return []Correlation{correlate(single.Label, &single, nil)}
+		}
+		if res.Multiple == nil {
+			issued := res.Single
+			return []Correlation{correlate(single.Label, &single, &issued)}
+		}
+	}
+	requests := req.Multiple
+	if requests == nil {
+		requests = []TokenRequest{req.Single}
+	}
+	var responses []TokenResponse
+	if res != nil {
+		responses = res.Multiple
+		if responses == nil {
+			responses = []TokenResponse{res.Single}
+		}
+	}
+	issued := make(map[string]*TokenResponse, len(responses))
+	for i := range responses {
+		issued[responses[i].Label] = &responses[i]
+	}
+	out := make([]Correlation, 0, len(requests))
+	requested := make(map[string]bool, len(requests))
+	for i := range requests {
+		label := requests[i].Label
+		requested[label] = true
+		out = append(out, correlate(label, &requests[i], issued[label]))
+	}
+	for i := range responses {
+		if !requested[responses[i].Label] {
+			out = append(out, correlate(responses[i].Label, nil, &responses[i]))
+		}
+	}
+	return out
+}
+
+// correlate compares the access of a requested and an issued token,
+// either of which may be nil.
+func correlate(label string, req *TokenRequest, res *TokenResponse) Correlation {
+	c := Correlation{Label: label, Request: req, Response: res}
+	switch {
+	case res == nil:
+		c.Outcome = Denied
+		c.Missing = req.Access
+	case req == nil:
+		c.Outcome = Unrequested
+		c.Extra = res.Access
+	default:
+		c.Missing = Difference(req.Access, res.Access)
+		c.Extra = Difference(res.Access, req.Access)
+		if len(c.Missing) > 0 {
+			c.Outcome = Downscoped
+		}
+	}
+	return c
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCorrelate(t *testing.T) {
+	read := AccessRight{Type: "photo-api", Actions: []string{"read"}}
+	write := AccessRight{Type: "photo-api", Actions: []string{"read", "write"}}
+	meta := AccessRight{Ref: "dolphin-metadata"}
+	type result struct {
+		Label   string
+		Outcome Outcome
+		Missing []AccessRight
+		Extra   []AccessRight
+	}
+	tests := []struct {
+		name string
+		req  ATRequest
+		res  *ATResponse
+		want []result
+	}{
+		{
+			name: "single",
+			req:  ATRequest{Single: TokenRequest{Access: []AccessRight{write}}},
+			res:  &ATResponse{Single: TokenResponse{Value: "x", Access: []AccessRight{read}}},
+			want: []result{{Outcome: Downscoped, Missing: []AccessRight{write}}},
+		},
+		{
+			name: "single denied",
+			req:  ATRequest{Single: TokenRequest{Access: []AccessRight{read}}},
+			want: []result{{Outcome: Denied, Missing: []AccessRight{read}}},
+		},
+		{
+			name: "multiple",
+			req: ATRequest{Multiple: []TokenRequest{
+				{Label: "photos", Access: []AccessRight{read}},
+				{Label: "meta", Access: []AccessRight{meta}},
+				{Label: "upload", Access: []AccessRight{write}},
+			}},
+			res: &ATResponse{Multiple: []TokenResponse{
+				{Value: "y", Label: "upload", Access: []AccessRight{read}},
+				{Value: "x", Label: "photos", Access: []AccessRight{write}},
+				{Value: "z", Label: "other", Access: []AccessRight{meta}},
+			}},
+			want: []result{
+				{Label: "photos", Outcome: Granted, Extra: []AccessRight{write}},
+				{Label: "meta", Outcome: Denied, Missing: []AccessRight{meta}},
+				{Label: "upload", Outcome: Downscoped, Missing: []AccessRight{write}},
+				{Label: "other", Outcome: Unrequested, Extra: []AccessRight{meta}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []result
+			for _, c := range Correlate(tt.req, tt.res) {
+				got = append(got, result{c.Label, c.Outcome, c.Missing, c.Extra})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Correlate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,16 +1,32 @@
 package models
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/bingxueshuang/gnap/internal/registry"
 )
 
 // ErrInvalidErrorCode is returned when the GNAPError.Code field is not
 // defined in the registry of gnap error codes.
 var ErrInvalidErrorCode = errors.New("invalid error code")
 
+// ErrDuplicateErrorCode is returned when registering an error code
+// that is already defined in the registry.
+var ErrDuplicateErrorCode = errors.New("duplicate error code")
+
+// ErrUnexpectedResponse is returned by [ReadError] when the response
+// body does not carry a gnap error.
+var ErrUnexpectedResponse = errors.New("unexpected response")
+
 // Errors corresponding to GNAP error codes
 var (
+	ErrGInvalidRequest      = errors.New("invalid_request")
 	ErrGInvalidClient       = errors.New("invalid_client")
 	ErrGInvalidInteraction  = errors.New("invalid_interaction")
 	ErrGInvalidFlag         = errors.New("invalid_flag")
@@ -25,7 +41,10 @@ var (
 	ErrGTooManyAttempts     = errors.New("too_many_attempts")
 )
 
-// DefaultDescription is the default description for error codes.
+// DefaultDescription is the default description for error codes. It is
+// read whenever an error without description is formatted, so changes
+// take effect at once; they must not be made concurrently with the use
+// of errors.
 var DefaultDescription = map[string]string{
 	"invalid_request":            "The request is missing a required parameter, includes an invalid parameter value or is otherwise malformed.",
 	"invalid_client":             "The request was made from a client that was not recognized or allowed by the AS, or the client's signature validation failed.",
@@ -42,20 +61,56 @@ var DefaultDescription = map[string]string{
 	"too_many_attempts":          "A limit has been reached in the total number of reasonable attempts.",
 }
 
-// errorRegistry denotes the IANA registry for GNAP error codes.
-var errorRegistry = map[string]error{
-	"invalid_client":             ErrGInvalidClient,
-	"invalid_interaction":        ErrGInvalidInteraction,
-	"invalid_flag":               ErrGInvalidFlag,
-	"invalid_rotation":           ErrGInvalidRotation,
-	"key_rotation_not_supported": ErrGKRNotSupported,
-	"invalid_continuation":       ErrGInvalidContinuation,
-	"user_denied":                ErrGUserDenied,
-	"request_denied":             ErrGRequestDenied,
-	"unknown_user":               ErrGUnknownUser,
-	"unknown_interaction":        ErrGUnknownInteraction,
-	"too_fast":                   ErrGTooFast,
-	"too_many_attempts":          ErrGTooManyAttempts,
+// errorCode is an entry of the registry of gnap error codes.
+type errorCode struct {
+	err    error
+	desc   string // description of extension codes
+	status int
+}
+
+// errorRegistry denotes the IANA registry for GNAP error codes,
+// extended by [RegisterErrorCode].
+var errorRegistry = registry.New(map[string]errorCode{
+	"invalid_request":            {err: ErrGInvalidRequest, status: http.StatusBadRequest},
+	"invalid_client":             {err: ErrGInvalidClient, status: http.StatusUnauthorized},
+	"invalid_interaction":        {err: ErrGInvalidInteraction, status: http.StatusBadRequest},
+	"invalid_flag":               {err: ErrGInvalidFlag, status: http.StatusBadRequest},
+	"invalid_rotation":           {err: ErrGInvalidRotation, status: http.StatusBadRequest},
+	"key_rotation_not_supported": {err: ErrGKRNotSupported, status: http.StatusBadRequest},
+	"invalid_continuation":       {err: ErrGInvalidContinuation, status: http.StatusBadRequest},
+	"user_denied":                {err: ErrGUserDenied, status: http.StatusForbidden},
+	"request_denied":             {err: ErrGRequestDenied, status: http.StatusForbidden},
+	"unknown_user":               {err: ErrGUnknownUser, status: http.StatusBadRequest},
+	"unknown_interaction":        {err: ErrGUnknownInteraction, status: http.StatusBadRequest},
+	"too_fast":                   {err: ErrGTooFast, status: http.StatusTooManyRequests},
+	"too_many_attempts":          {err: ErrGTooManyAttempts, status: http.StatusTooManyRequests},
+})
+
+// lookupError returns the registry entry of the error code.
+func lookupError(code string) (errorCode, bool) {
+	return errorRegistry.Lookup(code)
+}
+
+// RegisterErrorCode registers an extension error code with its default
+// description and the HTTP status code of error responses carrying it.
+// A [GNAPError] with the code then matches GNAPError{Code: code} with
+// [errors.Is]. It returns [ErrDuplicateErrorCode] if the code is
+// already registered. An entry for the code in [DefaultDescription]
+// takes precedence over desc.
+func RegisterErrorCode(code, desc string, status int) error {
+	if code == "" || http.StatusText(status) == "" {
+		return ErrInvalidErrorCode
+	}
+	err := errorRegistry.Register(code, errorCode{err: errors.New(code), desc: desc, status: status})
+	if errors.Is(err, registry.ErrRegistered) {
+		return ErrDuplicateErrorCode
+	}
+	return err
+}
+
+// UnregisterErrorCode removes an extension error code from the registry.
+func UnregisterErrorCode(code string) error {
+	return errorRegistry.Unregister(code)
 }
 
 // GNAPError is the error occurred during the GNAP protocol.
@@ -64,9 +119,29 @@ type GNAPError struct {
 	Desc string `json:"description,omitempty"`
 }
 
+// UnmarshalJSON implements the [json.Unmarshaler] interface. Decodes
+// from an error object or, as allowed by RFC 9635, from a string
+// holding only the error code.
+func (e *GNAPError) UnmarshalJSON(data []byte) error {
+	var code string
+	err := json.Unmarshal(data, &code)
+	if err == nil { // error code only
+		*e = GNAPError{Code: code}
+		return nil
+	}
+	type Alias GNAPError
+	var alias Alias
+	err = json.Unmarshal(data, &alias)
+	if err != nil {
+		return err
+	}
+	*e = GNAPError(alias)
+	return nil
+}
+
 // Error implements error interface.
 func (e GNAPError) Error() string {
-	_, ok := errorRegistry[e.Code]
+	entry, ok := lookupError(e.Code)
 	if !ok {
 		return ErrInvalidErrorCode.Error()
 	}
@@ -74,12 +149,15 @@ func (e GNAPError) Error() string {
 	if desc == "" {
 		desc = DefaultDescription[e.Code]
 	}
+	if desc == "" {
+		desc = entry.desc
+	}
 	return fmt.Sprintf("%s: %v", e.Code, desc)
 }
 
 // Is implements [errors.Is].
 func (e GNAPError) Is(target error) bool {
-	_, valid := errorRegistry[e.Code]
+	_, valid := lookupError(e.Code)
 	if !valid {
 		return errors.Is(target, ErrInvalidErrorCode)
 	}
@@ -96,9 +174,65 @@ func (e GNAPError) Is(target error) bool {
 // status code, or [ErrInvalidErrorCode] if status code is
 // invalid.
 func (e GNAPError) Unwrap() error {
-	err, ok := errorRegistry[e.Code]
+	entry, ok := lookupError(e.Code)
 	if !ok {
-		err = ErrInvalidErrorCode
+		return ErrInvalidErrorCode
 	}
-	return err
+	return entry.err
+}
+
+// StatusCode returns the HTTP status code of an error response carrying
+// the error. Error codes not in the registry map to 400 Bad Request.
+func (e GNAPError) StatusCode() int {
+	entry, ok := lookupError(e.Code)
+	if !ok {
+		return http.StatusBadRequest
+	}
+	return entry.status
+}
+
+// errorBody is the json body of an error response.
+type errorBody struct {
+	Error *GNAPError `json:"error"`
+}
+
+// Response renders the error as an HTTP response with the status code
+// of the error and a json body.
+func (e GNAPError) Response() *http.Response {
+	body, _ := json.Marshal(errorBody{&e})
+	status := e.StatusCode()
+	header := make(http.Header)
+	header.Set("Content-Type", "application/json")
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+	}
+}
+
+// WriteResponse writes the error to w with the status code of the
+// error and a json body.
+func (e GNAPError) WriteResponse(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(e.StatusCode())
+	_ = json.NewEncoder(w).Encode(errorBody{&e})
+}
+
+// ReadError decodes the error from the body of an AS response, in either
+// the object or the string form. If the body does not carry an error, it
+// returns an error wrapping [ErrUnexpectedResponse] with the status. The
+// caller remains responsible for closing the body.
+func ReadError(res *http.Response) error {
+	var body errorBody
+	err := json.NewDecoder(res.Body).Decode(&body)
+	if err != nil || body.Error == nil {
+		return fmt.Errorf("%w: %s", ErrUnexpectedResponse, res.Status)
+	}
+	return *body.Error
 }
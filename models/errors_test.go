@@ -4,6 +4,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -124,6 +128,196 @@ func TestGNAPError_Is(t *testing.T) {
 	}
 }
 
+func TestGNAPError_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    GNAPError
+		wantErr bool
+	}{
+		{
+			name: "object",
+			in:   `{"code":"user_denied","description":"The RO denied the request"}`,
+			want: GNAPError{"user_denied", "The RO denied the request"},
+		},
+		{
+			name: "string",
+			in:   `"too_fast"`,
+			want: GNAPError{Code: "too_fast"},
+		},
+		{
+			name:    "invalid",
+			in:      `["user_denied"]`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got GNAPError
+			err := json.Unmarshal([]byte(tt.in), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GNAPError.UnmarshalJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("GNAPError.UnmarshalJSON() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGNAPError_StatusCode(t *testing.T) {
+	tests := []struct {
+		name string
+		in   GNAPError
+		want int
+	}{
+		{
+			name: "bad request",
+			in:   GNAPError{Code: "invalid_request"},
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "client",
+			in:   GNAPError{Code: "invalid_client"},
+			want: http.StatusUnauthorized,
+		},
+		{
+			name: "denied",
+			in:   GNAPError{Code: "user_denied"},
+			want: http.StatusForbidden,
+		},
+		{
+			name: "too fast",
+			in:   GNAPError{Code: "too_fast"},
+			want: http.StatusTooManyRequests,
+		},
+		{
+			name: "invalid",
+			in:   GNAPError{Code: "wrong_code"},
+			want: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.in.StatusCode(); got != tt.want {
+				t.Errorf("GNAPError.StatusCode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGNAPError_Response(t *testing.T) {
+	in := GNAPError{"request_denied", "policy forbids access"}
+	res := in.Response()
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusForbidden {
+		t.Errorf("GNAPError.Response() status = %v, want %v", res.StatusCode, http.StatusForbidden)
+	}
+	if got := res.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("GNAPError.Response() Content-Type = %v, want application/json", got)
+	}
+	err := ReadError(res)
+	if got, ok := err.(GNAPError); !ok || got != in {
+		t.Errorf("ReadError() = %v, want %v", err, in)
+	}
+}
+
+func TestGNAPError_WriteResponse(t *testing.T) {
+	rec := httptest.NewRecorder()
+	GNAPError{Code: "too_many_attempts"}.WriteResponse(rec)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("GNAPError.WriteResponse() status = %v, want %v", rec.Code, http.StatusTooManyRequests)
+	}
+	err := ReadError(rec.Result())
+	if !errors.Is(err, ErrGTooManyAttempts) {
+		t.Errorf("ReadError() = %v, want %v", err, ErrGTooManyAttempts)
+	}
+}
+
+func TestReadError(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want error
+	}{
+		{
+			name: "object",
+			body: `{"error":{"code":"invalid_flag"}}`,
+			want: ErrGInvalidFlag,
+		},
+		{
+			name: "string",
+			body: `{"error":"unknown_user"}`,
+			want: ErrGUnknownUser,
+		},
+		{
+			name: "no error",
+			body: `{"continue":{}}`,
+			want: ErrUnexpectedResponse,
+		},
+		{
+			name: "not json",
+			body: `<html></html>`,
+			want: ErrUnexpectedResponse,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := &http.Response{
+				Status:     "400 Bad Request",
+				StatusCode: http.StatusBadRequest,
+				Body:       io.NopCloser(strings.NewReader(tt.body)),
+			}
+			if err := ReadError(res); !errors.Is(err, tt.want) {
+				t.Errorf("ReadError() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegisterErrorCode(t *testing.T) {
+	const code = "test_insufficient_funds"
+	err := RegisterErrorCode(code, "The account balance is too low.", http.StatusPaymentRequired)
+	if err != nil {
+		t.Fatalf("RegisterErrorCode() error = %v", err)
+	}
+	t.Cleanup(func() { _ = UnregisterErrorCode(code) })
+	gerr := GNAPError{Code: code}
+	if got := gerr.Error(); got != code+": The account balance is too low." {
+		t.Errorf("GNAPError.Error() = %v", got)
+	}
+	if got := gerr.StatusCode(); got != http.StatusPaymentRequired {
+		t.Errorf("GNAPError.StatusCode() = %v, want %v", got, http.StatusPaymentRequired)
+	}
+	if !errors.Is(gerr, GNAPError{Code: code}) || errors.Is(gerr, ErrInvalidErrorCode) {
+		t.Errorf("errors.Is() does not match the registered code")
+	}
+	err = RegisterErrorCode(code, "", http.StatusBadRequest)
+	if !errors.Is(err, ErrDuplicateErrorCode) {
+		t.Errorf("RegisterErrorCode() error = %v, want %v", err, ErrDuplicateErrorCode)
+	}
+	err = RegisterErrorCode("test_bad_status", "", 999)
+	if !errors.Is(err, ErrInvalidErrorCode) {
+		t.Errorf("RegisterErrorCode() error = %v, want %v", err, ErrInvalidErrorCode)
+	}
+	if err = UnregisterErrorCode("invalid_request"); err == nil {
+		t.Errorf("UnregisterErrorCode() of standard code error = nil")
+	}
+	if err = UnregisterErrorCode(code); err != nil || !errors.Is(gerr, ErrInvalidErrorCode) {
+		t.Errorf("UnregisterErrorCode() error = %v, code still registered", err)
+	}
+}
+
+func TestDefaultDescription(t *testing.T) {
+	const code = "too_fast"
+	prev := DefaultDescription[code]
+	t.Cleanup(func() { DefaultDescription[code] = prev })
+	DefaultDescription[code] = "Slow down."
+	if got := (GNAPError{Code: code}).Error(); got != "too_fast: Slow down." {
+		t.Errorf("GNAPError.Error() = %v, want the updated description", got)
+	}
+}
+
 // ExampleGNAPError decodes a json data into GNAPError and
 // checks that error is equivalent to ErrGInvalidClient.
 func ExampleGNAPError() {
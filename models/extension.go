@@ -0,0 +1,157 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// Extensions holds the members of a json object that are not defined
+// by the model, keyed by member name, so that they survive decoding and
+// re-encoding. Members defined by the model take precedence over
+// extensions of the same name when encoding.
+type Extensions map[string]json.RawMessage
+
+// Has reports whether the extension member is present.
+func (e Extensions) Has(name string) bool {
+	_, ok := e[name]
+	return ok
+}
+
+// Get decodes the extension member into v. It reports whether the
+// member is present.
+func (e Extensions) Get(name string, v any) (bool, error) {
+	raw, ok := e[name]
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(raw, v)
+}
+
+// Set encodes v as the extension member, allocating the map if needed.
+func (e *Extensions) Set(name string, v any) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if *e == nil {
+		*e = make(Extensions)
+	}
+	(*e)[name] = raw
+	return nil
+}
+
+// Delete removes the extension member.
+func (e Extensions) Delete(name string) {
+	delete(e, name)
+}
+
+// Extension is a typed accessor for a known extension member, declared
+// once by the profile or deployment defining it:
+//
+//	var Currency = models.Extension[string]{Name: "currency"}
+//
+//	cur, ok, err := Currency.Get(right.Extensions)
+type Extension[T any] struct {
+	Name string
+}
+
+// Get decodes the extension member. It reports whether the member is
+// present.
+func (x Extension[T]) Get(e Extensions) (v T, ok bool, err error) {
+	ok, err = e.Get(x.Name, &v)
+	return
+}
+
+// Set encodes v as the extension member.
+func (x Extension[T]) Set(e *Extensions, v T) error {
+	return e.Set(x.Name, v)
+}
+
+// knownMembers caches the json member names of the model types.
+var knownMembers sync.Map // map[reflect.Type]map[string]bool
+
+// members returns the json member names of the fields of struct type t.
+func members(t reflect.Type) map[string]bool {
+	if m, ok := knownMembers.Load(t); ok {
+		return m.(map[string]bool)
+	}
+	m := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		switch {
+		case name == "-" || !f.IsExported():
+			continue
+		case name == "":
+			name = f.Name
+		}
+		m[name] = true
+	}
+	knownMembers.Store(t, m)
+	return m
+}
+
+// unmarshalExtensions decodes the json object into v, a pointer to a
+// struct, and returns the members that are not fields of the struct.
+func unmarshalExtensions(data []byte, v any) (Extensions, error) {
+	err := json.Unmarshal(data, v)
+	if err != nil {
+		return nil, err
+	}
+	var obj map[string]json.RawMessage
+	err = json.Unmarshal(data, &obj)
+	if err != nil {
+		return nil, err
+	}
+	known := members(reflect.TypeOf(v).Elem())
+	var ext Extensions
+	for name, raw := range obj {
+		if known[name] {
+			continue
+		}
+		if ext == nil {
+			ext = make(Extensions)
+		}
+		ext[name] = raw
+	}
+	return ext, nil
+}
+
+// marshalExtensions encodes v, a struct, followed by the extension
+// members in lexical order.
+func marshalExtensions(v any, ext Extensions) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || len(ext) == 0 {
+		return data, err
+	}
+	known := members(reflect.TypeOf(v))
+	names := maps.Keys(ext)
+	slices.Sort(names)
+	var buf bytes.Buffer
+	buf.Write(data[:len(data)-1])
+	for _, name := range names {
+		if known[name] {
+			continue
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(name)
+		buf.Write(key)
+		buf.WriteByte(':')
+		raw := ext[name]
+		if !json.Valid(raw) {
+			return nil, fmt.Errorf("extension %q: %w", name, ErrInvalidValue)
+		}
+		buf.Write(raw)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,112 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestExtensions_roundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    string
+		model func() any
+	}{
+		{
+			name:  "grant request",
+			in:    `{"access_token":{"access":["dolphin-metadata"]},"client":"7C7C4AZ9KHRS6X63AJAO","example_audit":{"trace":"a1b2"},"example_profile":"fapi"}`,
+			model: func() any { return new(GrantRequest) },
+		},
+		{
+			name:  "grant response",
+			in:    `{"continue":{"uri":"https://server.example.com/continue","access_token":{"value":"80UPRY5NM33OMUKMKSKU"}},"example_hint":[1,2]}`,
+			model: func() any { return new(GrantResponse) },
+		},
+		{
+			name:  "access right",
+			in:    `{"type":"financial-transaction","actions":["withdraw"],"identifier":"account-14-32-32-3","currency":"USD"}`,
+			model: func() any { return new(AccessRight) },
+		},
+		{
+			name:  "client instance",
+			in:    `{"key":"7C7C4AZ9KHRS6X63AJAO","example_attestation":"eyJhbGciOiJFUzI1NiJ9"}`,
+			model: func() any { return new(ClientInstance) },
+		},
+		{
+			name:  "only extensions",
+			in:    `{"start":["redirect"],"example_mode":true}`,
+			model: func() any { return new(IARequest) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model := tt.model()
+			err := json.Unmarshal([]byte(tt.in), model)
+			if err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			got, err := json.Marshal(model)
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+			if string(got) != tt.in {
+				t.Errorf("json.Marshal() =\n%s\nwant\n%s", got, tt.in)
+			}
+		})
+	}
+}
+
+func TestExtensions_unknownOnly(t *testing.T) {
+	var req GrantRequest
+	err := json.Unmarshal([]byte(`{"client":"7C7C4AZ9KHRS6X63AJAO","example_profile":"fapi"}`), &req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Extensions{"example_profile": json.RawMessage(`"fapi"`)}
+	if !reflect.DeepEqual(req.Extensions, want) {
+		t.Errorf("GrantRequest.Extensions = %s, want %s", req.Extensions, want)
+	}
+	var none TokenRequest
+	_ = json.Unmarshal([]byte(`{"access":["read"]}`), &none)
+	if none.Extensions != nil {
+		t.Errorf("TokenRequest.Extensions = %v, want nil", none.Extensions)
+	}
+}
+
+func TestExtension(t *testing.T) {
+	type trace struct {
+		ID string `json:"id"`
+	}
+	audit := Extension[trace]{Name: "example_audit"}
+	var req GrantRequest
+	req.Client.Ref = "7C7C4AZ9KHRS6X63AJAO"
+	if _, ok, _ := audit.Get(req.Extensions); ok {
+		t.Errorf("Extension.Get() ok = true for absent member")
+	}
+	err := audit.Set(&req.Extensions, trace{ID: "a1b2"})
+	if err != nil {
+		t.Fatalf("Extension.Set() error = %v", err)
+	}
+	data, _ := json.Marshal(req)
+	if want := `{"client":"7C7C4AZ9KHRS6X63AJAO","example_audit":{"id":"a1b2"}}`; string(data) != want {
+		t.Errorf("json.Marshal() = %s, want %s", data, want)
+	}
+	var dec GrantRequest
+	_ = json.Unmarshal(data, &dec)
+	got, ok, err := audit.Get(dec.Extensions)
+	if !ok || err != nil || got.ID != "a1b2" {
+		t.Errorf("Extension.Get() = %v, %v, %v, want {a1b2}, true, nil", got, ok, err)
+	}
+	// members of the model take precedence over extensions
+	_ = dec.Extensions.Set("client", "other")
+	data, _ = json.Marshal(dec)
+	if want := `{"client":"7C7C4AZ9KHRS6X63AJAO","example_audit":{"id":"a1b2"}}`; string(data) != want {
+		t.Errorf("json.Marshal() = %s, want %s", data, want)
+	}
+	dec.Extensions.Delete("client")
+	dec.Extensions["broken"] = json.RawMessage(`{`)
+	if _, err = json.Marshal(dec); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("json.Marshal() error = %v, want %v", err, ErrInvalidValue)
+	}
+}
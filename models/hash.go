@@ -3,9 +3,9 @@ package models
 import (
 	"crypto/sha256"
 	"crypto/sha512"
-	"encoding/json"
 	"errors"
 
+	"github.com/bingxueshuang/gnap/internal/registry"
 	"golang.org/x/crypto/blake2b"
 	"golang.org/x/crypto/blake2s"
 	"golang.org/x/crypto/sha3"
@@ -29,10 +29,9 @@ const (
 	BLAKE2b_512 HashMethod = "blake2b-512" // [RFC7693]
 )
 
-// hashFuncRegistry maps HashMethod to corresponding cryptographic
-// hash algorithm. The imports mentioned in the comments need to be
-// included before usage.
-var hashFuncRegistry = map[HashMethod]func([]byte) []byte{
+// hashMethods is the registry mapping HashMethod to corresponding
+// cryptographic hash algorithm.
+var hashMethods = registry.New(map[HashMethod]func([]byte) []byte{
 	SHA_256: func(data []byte) []byte { x := sha256.Sum256(data); return x[:] },
 	SHA_384: func(data []byte) []byte { x := sha512.Sum384(data); return x[:] },
 	SHA_512: func(data []byte) []byte { x := sha512.Sum512(data); return x[:] },
@@ -44,34 +43,44 @@ var hashFuncRegistry = map[HashMethod]func([]byte) []byte{
 	BLAKE2s_256: func(data []byte) []byte { x := blake2s.Sum256(data); return x[:] },
 	BLAKE2b_256: func(data []byte) []byte { x := blake2b.Sum256(data); return x[:] },
 	BLAKE2b_512: func(data []byte) []byte { x := blake2b.Sum512(data); return x[:] },
-}
+})
 
 // ErrInvalidHashMethod is returned when a hash method that is not
 // defined in the registry is encountered.
 var ErrInvalidHashMethod = errors.New("invalid hash method")
 
-// MarshalJSON implements [json.Marshaler] interface.
-func (hm HashMethod) MarshalJSON() ([]byte, error) {
-	_, ok := hashFuncRegistry[hm]
-	if !ok {
-		return nil, ErrInvalidHashMethod
+// RegisterHashMethod registers an extension hash method, with the
+// function computing its digest.
+func RegisterHashMethod(hm HashMethod, sum func([]byte) []byte) error {
+	if sum == nil {
+		return ErrInvalidHashMethod
 	}
-	return json.Marshal(string(hm))
+	return hashMethods.Register(hm, sum)
+}
+
+// UnregisterHashMethod unregisters an extension hash method.
+func UnregisterHashMethod(hm HashMethod) error {
+	return hashMethods.Unregister(hm)
+}
+
+// Valid reports whether the hash method is registered.
+func (hm HashMethod) Valid() bool {
+	return hashMethods.Contains(hm)
+}
+
+// Standard reports whether the hash method is a standard value.
+func (hm HashMethod) Standard() bool {
+	return hashMethods.Standard(hm)
+}
+
+// MarshalJSON implements the [json.Marshaler] interface.
+func (hm HashMethod) MarshalJSON() ([]byte, error) {
+	return registry.Marshal(hashMethods, hm, ErrInvalidHashMethod)
 }
 
 // UnmarshalJSON implements the [json.Unmarshaler] interface.
 func (hm *HashMethod) UnmarshalJSON(data []byte) error {
-	var hash string
-	err := json.Unmarshal(data, &hash)
-	if err != nil {
-		return err
-	}
-	_, ok := hashFuncRegistry[HashMethod(hash)]
-	if !ok {
-		return ErrInvalidHashMethod
-	}
-	*hm = HashMethod(hash)
-	return nil
+	return registry.Unmarshal(hashMethods, data, hm, ErrInvalidHashMethod)
 }
 
 // Sum calculates cryptographic hash digest
@@ -82,7 +91,7 @@ func (hm HashMethod) Sum(data []byte) []byte {
 		// SHA_256 is default
 		hm = SHA_256
 	}
-	hash, ok := hashFuncRegistry[hm]
+	hash, ok := hashMethods.Lookup(hm)
 	if ok {
 		return hash(data)
 	}
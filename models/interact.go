@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"errors"
 	"net/url"
+
+	"github.com/bingxueshuang/gnap/internal/registry"
 )
 
 // ErrInvalidStartMode is returned when a start mode
@@ -25,37 +27,42 @@ const (
 	ModeCodeURI  StartMode = "user_code_uri"
 )
 
-// startModesRegistry is a quick mapping of registry values
-// to verify that a string is valid start mode.
-var startModesRegistry = map[string]StartMode{
-	"redirect":      ModeRedirect,
-	"app":           ModeApp,
-	"user_code":     ModeCode,
-	"user_code_uri": ModeCodeURI,
+// startModes is the registry of start modes.
+var startModes = registry.New(map[StartMode]struct{}{
+	ModeRedirect: {},
+	ModeApp:      {},
+	ModeCode:     {},
+	ModeCodeURI:  {},
+})
+
+// RegisterStartMode registers an extension start mode.
+func RegisterStartMode(mode StartMode) error {
+	return startModes.Register(mode, struct{}{})
+}
+
+// UnregisterStartMode unregisters an extension start mode.
+func UnregisterStartMode(mode StartMode) error {
+	return startModes.Unregister(mode)
+}
+
+// Valid reports whether the start mode is registered.
+func (sm StartMode) Valid() bool {
+	return startModes.Contains(sm)
+}
+
+// Standard reports whether the start mode is a standard value.
+func (sm StartMode) Standard() bool {
+	return startModes.Standard(sm)
 }
 
-// MarshalJSON implements [json.Marshaler] interface.
+// MarshalJSON implements the [json.Marshaler] interface.
 func (sm StartMode) MarshalJSON() ([]byte, error) {
-	_, ok := startModesRegistry[string(sm)]
-	if !ok {
-		return nil, ErrInvalidStartMode
-	}
-	return json.Marshal(string(sm))
+	return registry.Marshal(startModes, sm, ErrInvalidStartMode)
 }
 
 // UnmarshalJSON implements the [json.Unmarshaler] interface.
 func (sm *StartMode) UnmarshalJSON(data []byte) error {
-	var mode string
-	err := json.Unmarshal(data, &mode)
-	if err != nil {
-		return err
-	}
-	_, ok := startModesRegistry[mode]
-	if !ok {
-		return ErrInvalidStartMode
-	}
-	*sm = StartMode(mode)
-	return nil
+	return registry.Unmarshal(startModes, data, sm, ErrInvalidStartMode)
 }
 
 // FinishMethod indicates how the client instance can
@@ -69,55 +76,104 @@ const (
 	MethodRedirect FinishMethod = "redirect"
 )
 
-// finishMethodRegistry is a quick mapping to
-// check if a string is a valid FinishMethod.
-var finishMethodsRegistry = map[string]FinishMethod{
-	"push":     MethodPush,
-	"redirect": MethodRedirect,
+// finishMethods is the registry of finish methods.
+var finishMethods = registry.New(map[FinishMethod]struct{}{
+	MethodPush:     {},
+	MethodRedirect: {},
+})
+
+// RegisterFinishMethod registers an extension finish method.
+func RegisterFinishMethod(method FinishMethod) error {
+	return finishMethods.Register(method, struct{}{})
+}
+
+// UnregisterFinishMethod unregisters an extension finish method.
+func UnregisterFinishMethod(method FinishMethod) error {
+	return finishMethods.Unregister(method)
 }
 
-// MarshalJSON implements [json.Marshaler] interface.
+// Valid reports whether the finish method is registered.
+func (fm FinishMethod) Valid() bool {
+	return finishMethods.Contains(fm)
+}
+
+// Standard reports whether the finish method is a standard value.
+func (fm FinishMethod) Standard() bool {
+	return finishMethods.Standard(fm)
+}
+
+// MarshalJSON implements the [json.Marshaler] interface.
 func (fm FinishMethod) MarshalJSON() ([]byte, error) {
-	_, ok := finishMethodsRegistry[string(fm)]
-	if !ok {
-		return nil, ErrInvalidFinishMethod
-	}
-	return json.Marshal(string(fm))
+	return registry.Marshal(finishMethods, fm, ErrInvalidFinishMethod)
 }
 
-// UnmarshalJSON implements [json.Unmarshaler] interface.
+// UnmarshalJSON implements the [json.Unmarshaler] interface.
 func (fm *FinishMethod) UnmarshalJSON(data []byte) error {
-	var method string
-	err := json.Unmarshal(data, &method)
-	if err != nil {
-		return err
-	}
-	_, ok := finishMethodsRegistry[method]
-	if !ok {
-		return ErrInvalidFinishMethod
-	}
-	*fm = FinishMethod(method)
-	return nil
+	return registry.Unmarshal(finishMethods, data, fm, ErrInvalidFinishMethod)
 }
 
 // IARequest describes the modes that the client instance supports
 // for allowing the RO to interact with the AS and modes for the
 // client instance to receive updates when interaction is complete.
 type IARequest struct {
-	Start  []IAStart `json:"start"`
-	Finish *IAFinish `json:"finish,omitempty"`
-	Hints  *IAHints  `json:"hints,omitempty"`
+	Start      []IAStart  `json:"start"`
+	Finish     *IAFinish  `json:"finish,omitempty"`
+	Hints      *IAHints   `json:"hints,omitempty"`
+	Extensions Extensions `json:"-"`
+}
+
+// MarshalJSON implements the [json.Marshaler] interface. Extension
+// members are encoded after the members of the model.
+func (req IARequest) MarshalJSON() ([]byte, error) {
+	type Alias IARequest
+	return marshalExtensions(Alias(req), req.Extensions)
+}
+
+// UnmarshalJSON implements the [json.Unmarshaler] interface. Unknown
+// members are kept in Extensions.
+func (req *IARequest) UnmarshalJSON(data []byte) error {
+	type Alias IARequest
+	var alias Alias
+	ext, err := unmarshalExtensions(data, &alias)
+	if err != nil {
+		return err
+	}
+	alias.Extensions = ext
+	*req = IARequest(alias)
+	return nil
 }
 
 // IAResponse indicates that interaction through some set of
 // defined mechanisms needs to take place.
 type IAResponse struct {
-	Redirect  *URL       `json:"redirect,omitempty"`
-	App       *URL       `json:"app,omitempty"`
-	UserCode  string     `json:"user_code,omitempty"`
-	CodeURI   *IACodeURI `json:"user_code_uri,omitempty"`
-	Finish    string     `json:"finish,omitempty"`
-	ExpiresIn int        `json:"expires_in,omitempty"`
+	Redirect   *URL       `json:"redirect,omitempty"`
+	App        *URL       `json:"app,omitempty"`
+	UserCode   string     `json:"user_code,omitempty"`
+	CodeURI    *IACodeURI `json:"user_code_uri,omitempty"`
+	Finish     string     `json:"finish,omitempty"`
+	ExpiresIn  int        `json:"expires_in,omitempty"`
+	Extensions Extensions `json:"-"`
+}
+
+// MarshalJSON implements the [json.Marshaler] interface. Extension
+// members are encoded after the members of the model.
+func (res IAResponse) MarshalJSON() ([]byte, error) {
+	type Alias IAResponse
+	return marshalExtensions(Alias(res), res.Extensions)
+}
+
+// UnmarshalJSON implements the [json.Unmarshaler] interface. Unknown
+// members are kept in Extensions.
+func (res *IAResponse) UnmarshalJSON(data []byte) error {
+	type Alias IAResponse
+	var alias Alias
+	ext, err := unmarshalExtensions(data, &alias)
+	if err != nil {
+		return err
+	}
+	alias.Extensions = ext
+	*res = IAResponse(alias)
+	return nil
 }
 
 // IACallback represents the information conveyed to the
@@ -202,3 +258,80 @@ type IACodeURI struct {
 	Code string `json:"code"`
 	URI  URL    `json:"uri"`
 }
+
+// Validate checks the interaction request. At least one start mode is
+// required, and the finish method must be usable with the start modes.
+func (ia IARequest) Validate() error {
+	if len(ia.Start) == 0 {
+		return fieldError("start", ErrMissingField)
+	}
+	codeOnly := true
+	for i, start := range ia.Start {
+		if !startModes.Contains(start.Mode) {
+			return fieldError("start"+index(i), ErrInvalidStartMode)
+		}
+		if start.Mode != ModeCode && start.Mode != ModeCodeURI {
+			codeOnly = false
+		}
+	}
+	if ia.Finish == nil {
+		return nil
+	}
+	err := ia.Finish.Validate()
+	if err != nil {
+		return fieldError("finish", err)
+	}
+	// user codes are entered on a separate device, which
+	// cannot redirect back to the client instance.
+	if codeOnly && ia.Finish.Method == MethodRedirect {
+		return fieldError("finish.method", ErrConflictingFields)
+	}
+	return nil
+}
+
+// Validate checks the interaction finish parameters.
+func (f IAFinish) Validate() error {
+	if !finishMethods.Contains(f.Method) {
+		return fieldError("method", ErrInvalidFinishMethod)
+	}
+	err := validateURL(f.URI)
+	if err != nil {
+		return fieldError("uri", err)
+	}
+	if f.Nonce == "" {
+		return fieldError("nonce", ErrMissingField)
+	}
+	if f.HashMethod == "" {
+		return nil
+	}
+	if !hashMethods.Contains(f.HashMethod) {
+		return fieldError("hash_method", ErrInvalidHashMethod)
+	}
+	return nil
+}
+
+// Validate checks the interaction response.
+func (ia IAResponse) Validate() error {
+	if ia.Redirect != nil {
+		if err := validateURL(ia.Redirect); err != nil {
+			return fieldError("redirect", err)
+		}
+	}
+	if ia.App != nil {
+		if err := validateURL(ia.App); err != nil {
+			return fieldError("app", err)
+		}
+	}
+	if ia.CodeURI != nil {
+		if ia.CodeURI.Code == "" {
+			return fieldError("user_code_uri.code", ErrMissingField)
+		}
+		if err := validateURL(&ia.CodeURI.URI); err != nil {
+			return fieldError("user_code_uri.uri", err)
+		}
+	}
+	if ia.ExpiresIn < 0 {
+		return fieldError("expires_in", ErrInvalidValue)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidIntrospection is returned when an introspection request
+// is found to be malformed.
+var ErrInvalidIntrospection = errors.New("invalid introspection request")
+
+// RSIdentity identifies the resource server to the AS, either by
+// value (object with key) or by reference (string).
+type RSIdentity struct {
+	Key ClientKey `json:"key"`
+	Ref string    `json:"-"`
+}
+
+// MarshalJSON implements the [json.Marshaler] interface.
+func (rs RSIdentity) MarshalJSON() ([]byte, error) {
+	if rs.Ref != "" {
+		return json.Marshal(rs.Ref)
+	}
+	type Alias RSIdentity
+	return json.Marshal(Alias(rs))
+}
+
+// UnmarshalJSON implements the [json.Unmarshaler] interface.
+func (rs *RSIdentity) UnmarshalJSON(data []byte) error {
+	var ref string
+	err := json.Unmarshal(data, &ref)
+	if err == nil { // by reference
+		*rs = RSIdentity{Ref: ref}
+		return nil
+	}
+	type Alias RSIdentity
+	var alias Alias
+	err = json.Unmarshal(data, &alias)
+	if err != nil {
+		return err
+	}
+	*rs = RSIdentity(alias)
+	return nil
+}
+
+// IntrospectRequest represents the request of the RS to the
+// introspection endpoint of the AS.
+type IntrospectRequest struct {
+	AccessToken    string        `json:"access_token"`
+	Proof          ProofMethod   `json:"proof,omitempty"`
+	ResourceServer RSIdentity    `json:"resource_server"`
+	Access         []AccessRight `json:"access,omitempty"`
+	Extensions     Extensions    `json:"-"`
+}
+
+// MarshalJSON implements the [json.Marshaler] interface. Extension
+// members are encoded after the members of the model.
+func (req IntrospectRequest) MarshalJSON() ([]byte, error) {
+	type Alias IntrospectRequest
+	return marshalExtensions(Alias(req), req.Extensions)
+}
+
+// UnmarshalJSON implements the [json.Unmarshaler] interface. Unknown
+// members are kept in Extensions.
+func (req *IntrospectRequest) UnmarshalJSON(data []byte) error {
+	type Alias IntrospectRequest
+	var alias Alias
+	ext, err := unmarshalExtensions(data, &alias)
+	if err != nil {
+		return err
+	}
+	alias.Extensions = ext
+	*req = IntrospectRequest(alias)
+	return nil
+}
+
+// IntrospectResponse represents the information about the access
+// token returned by the AS. Only Active is present for tokens that
+// are not active.
+type IntrospectResponse struct {
+	Active     bool          `json:"active"`
+	Access     []AccessRight `json:"access,omitempty"`
+	Key        *ClientKey    `json:"key,omitempty"`
+	Flags      []TokenFlag   `json:"flags,omitempty"`
+	ExpiresAt  int64         `json:"exp,omitempty"`
+	IssuedAt   int64         `json:"iat,omitempty"`
+	Issuer     string        `json:"iss,omitempty"`
+	Subject    string        `json:"sub,omitempty"`
+	InstanceID string        `json:"instance_id,omitempty"`
+	Extensions Extensions    `json:"-"`
+}
+
+// MarshalJSON implements the [json.Marshaler] interface. Extension
+// members are encoded after the members of the model.
+func (res IntrospectResponse) MarshalJSON() ([]byte, error) {
+	type Alias IntrospectResponse
+	return marshalExtensions(Alias(res), res.Extensions)
+}
+
+// UnmarshalJSON implements the [json.Unmarshaler] interface. Unknown
+// members are kept in Extensions.
+func (res *IntrospectResponse) UnmarshalJSON(data []byte) error {
+	type Alias IntrospectResponse
+	var alias Alias
+	ext, err := unmarshalExtensions(data, &alias)
+	if err != nil {
+		return err
+	}
+	alias.Extensions = ext
+	*res = IntrospectResponse(alias)
+	return nil
+}
+
+// Validate checks the introspection request. The error wraps
+// [ErrInvalidIntrospection] and the offending field.
+func (req IntrospectRequest) Validate() error {
+	err := req.validate()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidIntrospection, err)
+	}
+	return nil
+}
+
+// validate checks the fields of the introspection request.
+func (req IntrospectRequest) validate() error {
+	if req.AccessToken == "" {
+		return fieldError("access_token", ErrMissingField)
+	}
+	if req.Proof != "" {
+		if !proofMethods.Contains(req.Proof) {
+			return fieldError("proof", ErrInvalidProofMethod)
+		}
+	}
+	if req.ResourceServer.Ref == "" {
+		err := req.ResourceServer.Key.Validate()
+		if err != nil {
+			return fieldError("resource_server.key", err)
+		}
+	}
+	for i, right := range req.Access {
+		err := right.Validate()
+		if err != nil {
+			return fieldError("access"+index(i), err)
+		}
+	}
+	return nil
+}
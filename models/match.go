@@ -0,0 +1,328 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/bingxueshuang/gnap/internal/registry"
+	"golang.org/x/exp/slices"
+)
+
+// AccessMatcher defines the semantics of comparing access rights of one
+// API type. Both arguments of its methods are guaranteed to have the
+// same Type and to be given by value (not by reference).
+type AccessMatcher interface {
+	// Covers reports whether the granted right allows everything
+	// the requested right asks for.
+	Covers(granted, requested AccessRight) bool
+	// Intersect computes the access allowed by both rights. It returns
+	// false if the rights have nothing in common.
+	Intersect(a, b AccessRight) (AccessRight, bool)
+}
+
+// StandardMatcher implements [AccessMatcher] for the common fields of
+// [AccessRight]. An omitted field places no restriction on its dimension,
+// so a granted right without Actions allows every action. Locations are
+// compared as URL prefixes on path segment boundaries; Actions, Datatypes
+// and Privileges as sets; Identifier for equality.
+type StandardMatcher struct {
+	// Implied maps an action to the actions it implies, so that
+	// for instance "write" may also allow "read".
+	Implied map[string][]string
+	// ExactLocations disables the prefix matching of locations.
+	ExactLocations bool
+}
+
+// matchers is the registry of access matchers keyed by type. No matcher
+// is standard.
+var matchers = registry.New(map[string]AccessMatcher{})
+
+// RegisterMatcher registers the matcher used for access rights of the
+// given type. Types without a registered matcher use [StandardMatcher].
+func RegisterMatcher(typ string, m AccessMatcher) error {
+	if m == nil {
+		return fmt.Errorf("matcher of %q: %w", typ, ErrInvalidValue)
+	}
+	return matchers.Register(typ, m)
+}
+
+// UnregisterMatcher unregisters the matcher of the given type.
+func UnregisterMatcher(typ string) error {
+	return matchers.Unregister(typ)
+}
+
+// matcherFor returns the matcher registered for typ.
+func matcherFor(typ string) AccessMatcher {
+	m, ok := matchers.Lookup(typ)
+	if !ok {
+		return StandardMatcher{}
+	}
+	return m
+}
+
+// Covers reports whether r, as a granted right, allows everything that
+// the requested right asks for. Rights by reference only cover the same
+// reference; expand them beforehand to compare by value.
+func (r AccessRight) Covers(requested AccessRight) bool {
+	if r.Ref != "" || requested.Ref != "" {
+		return r.Ref == requested.Ref
+	}
+	if r.Type != requested.Type {
+		return false
+	}
+	return matcherFor(r.Type).Covers(r, requested)
+}
+
+// Intersect computes the access allowed by both r and other.
+// It returns false if they have nothing in common.
+func (r AccessRight) Intersect(other AccessRight) (AccessRight, bool) {
+	if r.Ref != "" || other.Ref != "" {
+		return r, r.Ref == other.Ref
+	}
+	if r.Type != other.Type {
+		return AccessRight{}, false
+	}
+	return matcherFor(r.Type).Intersect(r, other)
+}
+
+// IsSubset reports whether every right in requested is covered by
+// some right in granted.
+func IsSubset(requested, granted []AccessRight) bool {
+	for _, req := range requested {
+		covered := slices.ContainsFunc(granted, func(g AccessRight) bool {
+			return g.Covers(req)
+		})
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
+
+// Difference computes the rights in a that are not covered by any
+// right in b.
+func Difference(a, b []AccessRight) []AccessRight {
+	var out []AccessRight
+	for _, x := range a {
+		covered := slices.ContainsFunc(b, func(y AccessRight) bool {
+			return y.Covers(x)
+		})
+		if !covered {
+			out = append(out, x)
+		}
+	}
+	return out
+}
+
+// Intersection computes the access allowed by both a and b.
+func Intersection(a, b []AccessRight) []AccessRight {
+	var out []AccessRight
+	for _, x := range a {
+		for _, y := range b {
+			if z, ok := x.Intersect(y); ok {
+				out = append(out, z)
+			}
+		}
+	}
+	return reduce(out)
+}
+
+// Union computes the access allowed by either a or b, leaving out the
+// rights already covered by others.
+func Union(a, b []AccessRight) []AccessRight {
+	out := make([]AccessRight, 0, len(a)+len(b))
+	out = append(out, a...)
+	out = append(out, b...)
+	return reduce(out)
+}
+
+// reduce removes the rights covered by another right of the list.
+func reduce(rights []AccessRight) []AccessRight {
+	var out []AccessRight
+	for i, r := range rights {
+		redundant := false
+		for j, other := range rights {
+			if i == j || !other.Covers(r) {
+				continue
+			}
+			// of two equivalent rights keep the first one
+			if !r.Covers(other) || j < i {
+				redundant = true
+				break
+			}
+		}
+		if !redundant {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// Covers implements [AccessMatcher] interface.
+func (m StandardMatcher) Covers(granted, requested AccessRight) bool {
+	if granted.Identifier != "" && granted.Identifier != requested.Identifier {
+		return false
+	}
+	return coversSet(m.expand(granted.Actions), requested.Actions) &&
+		m.coversLocations(granted.Locations, requested.Locations) &&
+		coversSet(granted.Datatypes, requested.Datatypes) &&
+		coversSet(granted.Privileges, requested.Privileges)
+}
+
+// Intersect implements [AccessMatcher] interface.
+func (m StandardMatcher) Intersect(a, b AccessRight) (AccessRight, bool) {
+	out := AccessRight{Type: a.Type, Identifier: a.Identifier}
+	if a.Identifier == "" {
+		out.Identifier = b.Identifier
+	} else if b.Identifier != "" && a.Identifier != b.Identifier {
+		return AccessRight{}, false
+	}
+	var ok [4]bool
+	out.Actions, ok[0] = m.intersectActions(a.Actions, b.Actions)
+	out.Locations, ok[1] = m.intersectLocations(a.Locations, b.Locations)
+	out.Datatypes, ok[2] = intersectSet(a.Datatypes, b.Datatypes)
+	out.Privileges, ok[3] = intersectSet(a.Privileges, b.Privileges)
+	if ok != [4]bool{true, true, true, true} {
+		return AccessRight{}, false
+	}
+	return out, true
+}
+
+// expand adds the implied actions to the list of actions.
+func (m StandardMatcher) expand(actions []string) []string {
+	if len(m.Implied) == 0 || len(actions) == 0 {
+		return actions
+	}
+	out := slices.Clone(actions)
+	for i := 0; i < len(out); i++ { // out grows while iterating
+		for _, implied := range m.Implied[out[i]] {
+			if !slices.Contains(out, implied) {
+				out = append(out, implied)
+			}
+		}
+	}
+	return out
+}
+
+// intersectActions intersects actions taking implied actions into account.
+func (m StandardMatcher) intersectActions(a, b []string) ([]string, bool) {
+	if len(a) == 0 || len(b) == 0 {
+		return intersectSet(a, b)
+	}
+	return intersectSet(m.expand(a), m.expand(b))
+}
+
+// coversLocations reports whether every requested location falls under
+// some granted location.
+func (m StandardMatcher) coversLocations(granted, requested []string) bool {
+	if len(granted) == 0 {
+		return true
+	}
+	if len(requested) == 0 {
+		return false
+	}
+	for _, req := range requested {
+		if !slices.ContainsFunc(granted, func(g string) bool {
+			return m.locationCovers(g, req)
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+// intersectLocations keeps the more specific of every pair of
+// overlapping locations.
+func (m StandardMatcher) intersectLocations(a, b []string) ([]string, bool) {
+	if len(a) == 0 || len(b) == 0 {
+		return intersectSet(a, b)
+	}
+	var out []string
+	for _, x := range a {
+		for _, y := range b {
+			var loc string
+			switch {
+			case m.locationCovers(x, y):
+				loc = y
+			case m.locationCovers(y, x):
+				loc = x
+			default:
+				continue
+			}
+			if !slices.Contains(out, loc) {
+				out = append(out, loc)
+			}
+		}
+	}
+	return out, len(out) > 0
+}
+
+// locationCovers reports whether the location req is under the location
+// granted. The locations must have the same scheme and host, and the
+// cleaned path of req must be the path of granted or lie under it on a
+// path segment boundary. A granted location with query or fragment, or
+// one that is not an absolute url, covers only itself.
+func (m StandardMatcher) locationCovers(granted, req string) bool {
+	if granted == req {
+		return true
+	}
+	if m.ExactLocations {
+		return false
+	}
+	g, err := url.Parse(granted)
+	if err != nil || !g.IsAbs() || g.Opaque != "" || g.RawQuery != "" || g.Fragment != "" {
+		return false
+	}
+	r, err := url.Parse(req)
+	if err != nil || !r.IsAbs() || r.Opaque != "" {
+		return false
+	}
+	if !strings.EqualFold(g.Scheme, r.Scheme) || !strings.EqualFold(g.Host, r.Host) || g.User.String() != r.User.String() {
+		return false
+	}
+	gp, rp := cleanPath(g.Path), cleanPath(r.Path)
+	return rp == gp || gp == "/" || strings.HasPrefix(rp, gp+"/")
+}
+
+// cleanPath returns the path with dot segments and duplicate slashes
+// resolved, where an empty path is the root.
+func cleanPath(p string) string {
+	return path.Clean("/" + p)
+}
+
+// coversSet reports whether requested is a subset of granted, where an
+// empty granted set places no restriction.
+func coversSet(granted, requested []string) bool {
+	if len(granted) == 0 {
+		return true
+	}
+	if len(requested) == 0 {
+		return false
+	}
+	for _, r := range requested {
+		if !slices.Contains(granted, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// intersectSet intersects two sets, where an empty set places no
+// restriction. It returns false if the restricted result is empty.
+func intersectSet(a, b []string) ([]string, bool) {
+	if len(a) == 0 {
+		return slices.Clone(b), true
+	}
+	if len(b) == 0 {
+		return slices.Clone(a), true
+	}
+	var out []string
+	for _, x := range a {
+		if slices.Contains(b, x) && !slices.Contains(out, x) {
+			out = append(out, x)
+		}
+	}
+	return out, len(out) > 0
+}
@@ -0,0 +1,175 @@
+package models
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestAccessRight_Covers(t *testing.T) {
+	granted := AccessRight{
+		Type:      "photo-api",
+		Actions:   []string{"read", "write"},
+		Locations: []string{"https://server.example.net/photos/"},
+		Datatypes: []string{"metadata", "images"},
+	}
+	tests := []struct {
+		name      string
+		granted   AccessRight
+		requested AccessRight
+		want      bool
+	}{
+		{
+			name:    "subset",
+			granted: granted,
+			requested: AccessRight{
+				Type:      "photo-api",
+				Actions:   []string{"read"},
+				Locations: []string{"https://server.example.net/photos/2023"},
+				Datatypes: []string{"metadata"},
+			},
+			want: true,
+		},
+		{
+			name:      "type",
+			granted:   granted,
+			requested: AccessRight{Type: "financial-transaction"},
+		},
+		{
+			name:    "action",
+			granted: granted,
+			requested: AccessRight{
+				Type:      "photo-api",
+				Actions:   []string{"delete"},
+				Locations: []string{"https://server.example.net/photos/"},
+				Datatypes: []string{"images"},
+			},
+		},
+		{
+			name:    "unrestricted request",
+			granted: granted,
+			requested: AccessRight{
+				Type:      "photo-api",
+				Locations: []string{"https://server.example.net/photos/"},
+				Datatypes: []string{"images"},
+			},
+		},
+		{
+			name:      "segment boundary",
+			granted:   AccessRight{Type: "photo-api", Locations: []string{"https://server.example.net/photos"}},
+			requested: AccessRight{Type: "photo-api", Locations: []string{"https://server.example.net/photos2"}},
+		},
+		{
+			name:      "segment",
+			granted:   AccessRight{Type: "photo-api", Locations: []string{"https://server.example.net/photos"}},
+			requested: AccessRight{Type: "photo-api", Locations: []string{"https://server.example.net/photos/1"}},
+			want:      true,
+		},
+		{
+			name:      "dot segments",
+			granted:   AccessRight{Type: "photo-api", Locations: []string{"https://server.example.net/photos/"}},
+			requested: AccessRight{Type: "photo-api", Locations: []string{"https://server.example.net/photos/../admin"}},
+		},
+		{
+			name:      "clean path",
+			granted:   AccessRight{Type: "photo-api", Locations: []string{"https://server.example.net/photos"}},
+			requested: AccessRight{Type: "photo-api", Locations: []string{"https://server.example.net/photos/./1"}},
+			want:      true,
+		},
+		{
+			name:      "host prefix",
+			granted:   AccessRight{Type: "photo-api", Locations: []string{"https://server.example.net"}},
+			requested: AccessRight{Type: "photo-api", Locations: []string{"https://server.example.net.attacker.example/photos"}},
+		},
+		{
+			name:      "userinfo",
+			granted:   AccessRight{Type: "photo-api", Locations: []string{"https://server.example.net"}},
+			requested: AccessRight{Type: "photo-api", Locations: []string{"https://server.example.net@attacker.example/photos"}},
+		},
+		{
+			name:      "scheme",
+			granted:   AccessRight{Type: "photo-api", Locations: []string{"https://server.example.net/photos"}},
+			requested: AccessRight{Type: "photo-api", Locations: []string{"http://server.example.net/photos/1"}},
+		},
+		{
+			name:      "root",
+			granted:   AccessRight{Type: "photo-api", Locations: []string{"https://server.example.net"}},
+			requested: AccessRight{Type: "photo-api", Locations: []string{"https://server.example.net/photos"}},
+			want:      true,
+		},
+		{
+			name:      "identifier",
+			granted:   AccessRight{Type: "financial-transaction", Identifier: "account-14-32-32-3"},
+			requested: AccessRight{Type: "financial-transaction", Identifier: "account-14-32-32-4"},
+		},
+		{
+			name:      "reference",
+			granted:   AccessRight{Ref: "dolphin-metadata"},
+			requested: AccessRight{Ref: "dolphin-metadata"},
+			want:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.granted.Covers(tt.requested); got != tt.want {
+				t.Errorf("AccessRight.Covers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegisterMatcher(t *testing.T) {
+	if err := RegisterMatcher("test-api", StandardMatcher{Implied: map[string][]string{"write": {"read"}}}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = UnregisterMatcher("test-api") })
+	if err := RegisterMatcher("other-api", nil); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("RegisterMatcher() error = %v, want %v", err, ErrInvalidValue)
+	}
+	granted := AccessRight{Type: "test-api", Actions: []string{"write"}}
+	requested := AccessRight{Type: "test-api", Actions: []string{"read"}}
+	if !granted.Covers(requested) {
+		t.Errorf("AccessRight.Covers() = false, want implied action to be covered")
+	}
+	got, ok := granted.Intersect(requested)
+	want := AccessRight{Type: "test-api", Actions: []string{"read"}}
+	if !ok || !reflect.DeepEqual(got, want) {
+		t.Errorf("AccessRight.Intersect() = %v, %v, want %v", got, ok, want)
+	}
+}
+
+func TestIntersection(t *testing.T) {
+	a := []AccessRight{
+		{Type: "photo-api", Actions: []string{"read", "write"}, Locations: []string{"https://server.example.net/"}},
+		{Type: "financial-transaction", Identifier: "account-14-32-32-3"},
+	}
+	b := []AccessRight{
+		{Type: "photo-api", Actions: []string{"read", "delete"}, Locations: []string{"https://server.example.net/photos"}},
+		{Type: "financial-transaction", Identifier: "account-99"},
+	}
+	want := []AccessRight{
+		{Type: "photo-api", Actions: []string{"read"}, Locations: []string{"https://server.example.net/photos"}},
+	}
+	if got := Intersection(a, b); !reflect.DeepEqual(got, want) {
+		t.Errorf("Intersection() = %v, want %v", got, want)
+	}
+}
+
+func TestUnion(t *testing.T) {
+	a := []AccessRight{{Type: "photo-api", Actions: []string{"read", "write"}}}
+	b := []AccessRight{
+		{Type: "photo-api", Actions: []string{"read"}},
+		{Type: "financial-transaction"},
+	}
+	want := []AccessRight{a[0], b[1]}
+	got := Union(a, b)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Union() = %v, want %v", got, want)
+	}
+	if !IsSubset(b, got) || !IsSubset(a, got) {
+		t.Errorf("IsSubset() = false, want union to cover its operands")
+	}
+	if IsSubset(got, a) {
+		t.Errorf("IsSubset() = true, want false")
+	}
+}
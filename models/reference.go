@@ -0,0 +1,143 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// ErrUnknownReference is returned when an access right by reference
+// is not defined in the registry.
+var ErrUnknownReference = errors.New("unknown access reference")
+
+// AccessRegistry maps access references, such as "dolphin-metadata", to
+// the full access rights they stand for. It is safe for concurrent use
+// and the zero value is an empty registry ready to use.
+type AccessRegistry struct {
+	mu   sync.RWMutex
+	refs map[string]AccessRight
+}
+
+// Register defines ref as a reference to the access right given by value.
+func (r *AccessRegistry) Register(ref string, right AccessRight) error {
+	if ref == "" || right.Ref != "" || right.Type == "" {
+		return fmt.Errorf("register %q: %w", ref, ErrInvalidAccessRight)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.refs == nil {
+		r.refs = make(map[string]AccessRight)
+	}
+	r.refs[ref] = right
+	return nil
+}
+
+// Unregister removes the definition of ref.
+func (r *AccessRegistry) Unregister(ref string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.refs, ref)
+}
+
+// Lookup returns the access right that ref stands for.
+func (r *AccessRegistry) Lookup(ref string) (AccessRight, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	right, ok := r.refs[ref]
+	return right, ok
+}
+
+// Expand replaces the access rights by reference with their definitions.
+// It fails with [ErrUnknownReference] on the first undefined reference.
+func (r *AccessRegistry) Expand(rights []AccessRight) ([]AccessRight, error) {
+	out := make([]AccessRight, len(rights))
+	for i, right := range rights {
+		if right.Ref == "" {
+			out[i] = right
+			continue
+		}
+		def, ok := r.Lookup(right.Ref)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownReference, right.Ref)
+		}
+		out[i] = def
+	}
+	return out, nil
+}
+
+// Collapse replaces the access rights by value with a reference that
+// stands for an equivalent right. Rights without such a reference are
+// left by value.
+func (r *AccessRegistry) Collapse(rights []AccessRight) []AccessRight {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	refs := maps.Keys(r.refs)
+	slices.Sort(refs) // deterministic choice among equivalent references
+	out := make([]AccessRight, len(rights))
+	for i, right := range rights {
+		out[i] = right
+		if right.Ref != "" {
+			continue
+		}
+		for _, ref := range refs {
+			def := r.refs[ref]
+			if def.Covers(right) && right.Covers(def) {
+				out[i] = AccessRight{Ref: ref}
+				break
+			}
+		}
+	}
+	return out
+}
+
+// ExpandToken expands the access references of the token request in place.
+func (r *AccessRegistry) ExpandToken(req *TokenRequest) error {
+	access, err := r.Expand(req.Access)
+	if err != nil {
+		return err
+	}
+	req.Access = access
+	return nil
+}
+
+// ExpandGrant expands the access references of every token requested
+// by the grant request in place.
+func (r *AccessRegistry) ExpandGrant(req *GrantRequest) error {
+	if req.AccessToken == nil {
+		return nil
+	}
+	if req.AccessToken.Multiple == nil {
+		return r.ExpandToken(&req.AccessToken.Single)
+	}
+	for i := range req.AccessToken.Multiple {
+		err := r.ExpandToken(&req.AccessToken.Multiple[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CollapseToken collapses the access rights of the token response
+// to references in place.
+func (r *AccessRegistry) CollapseToken(res *TokenResponse) {
+	res.Access = r.Collapse(res.Access)
+}
+
+// CollapseGrant collapses the access rights of every token issued in
+// the grant response to references in place.
+func (r *AccessRegistry) CollapseGrant(res *GrantResponse) {
+	if res.AccessToken == nil {
+		return
+	}
+	if res.AccessToken.Multiple == nil {
+		r.CollapseToken(&res.AccessToken.Single)
+		return
+	}
+	for i := range res.AccessToken.Multiple {
+		r.CollapseToken(&res.AccessToken.Multiple[i])
+	}
+}
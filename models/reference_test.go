@@ -0,0 +1,48 @@
+package models
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestAccessRegistry(t *testing.T) {
+	metadata := AccessRight{
+		Type:      "photo-api",
+		Actions:   []string{"read"},
+		Locations: []string{"https://server.example.net/"},
+		Datatypes: []string{"metadata"},
+	}
+	var reg AccessRegistry
+	err := reg.Register("dolphin-metadata", metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = reg.Register("nested", AccessRight{Ref: "dolphin-metadata"})
+	if !errors.Is(err, ErrInvalidAccessRight) {
+		t.Errorf("AccessRegistry.Register() error = %v, want %v", err, ErrInvalidAccessRight)
+	}
+	other := AccessRight{Type: "financial-transaction", Identifier: "account-14-32-32-3"}
+
+	req, _ := NewTokenRequest([]AccessRight{{Ref: "dolphin-metadata"}, other})
+	err = reg.ExpandToken(&req)
+	if err != nil {
+		t.Fatalf("AccessRegistry.ExpandToken() error = %v", err)
+	}
+	want := []AccessRight{metadata, other}
+	if !reflect.DeepEqual(req.Access, want) {
+		t.Errorf("AccessRegistry.ExpandToken() = %v, want %v", req.Access, want)
+	}
+
+	_, err = reg.Expand([]AccessRight{{Ref: "unknown"}})
+	if !errors.Is(err, ErrUnknownReference) {
+		t.Errorf("AccessRegistry.Expand() error = %v, want %v", err, ErrUnknownReference)
+	}
+
+	res, _ := NewTokenResponse("OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0", want)
+	reg.CollapseToken(&res)
+	want = []AccessRight{{Ref: "dolphin-metadata"}, other}
+	if !reflect.DeepEqual(res.Access, want) {
+		t.Errorf("AccessRegistry.CollapseToken() = %v, want %v", res.Access, want)
+	}
+}
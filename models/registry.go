@@ -0,0 +1,21 @@
+package models
+
+import "github.com/bingxueshuang/gnap/internal/registry"
+
+// Registries of protocol values. Each enumerated type of the package,
+// such as [StartMode] or [TokenFlag], is backed by a registry holding
+// the values defined by the specification. Its Register function adds
+// an extension value, so that it is accepted when encoding and decoding,
+// and its Unregister function removes it again; standard values cannot
+// be removed. The Valid method of the type reports whether a value is
+// registered, and the Standard method whether it is defined by the
+// specification rather than registered as an extension. Unregistered
+// values fail to encode and decode with the invalid error of the type,
+// such as [ErrInvalidStartMode].
+//
+// Errors returned when registering and unregistering extension values.
+var (
+	ErrEmptyValue        = registry.ErrEmpty
+	ErrAlreadyRegistered = registry.ErrRegistered
+	ErrStandardValue     = registry.ErrStandard
+)
@@ -0,0 +1,130 @@
+package models
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestRegisterStartMode(t *testing.T) {
+	const mode StartMode = "example_custom_mode"
+	var got IARequest
+	data := []byte(`{"start":["redirect","example_custom_mode"]}`)
+	if err := json.Unmarshal(data, &got); err == nil {
+		t.Fatalf("json.Unmarshal() error = nil before registration")
+	}
+	if err := RegisterStartMode(mode); err != nil {
+		t.Fatalf("RegisterStartMode() error = %v", err)
+	}
+	defer UnregisterStartMode(mode)
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if !mode.Valid() || mode.Standard() {
+		t.Errorf("StartMode.Valid(), Standard() = %v, %v, want true, false", mode.Valid(), mode.Standard())
+	}
+	if !ModeRedirect.Standard() {
+		t.Errorf("StartMode.Standard() = false for %v", ModeRedirect)
+	}
+	if err := RegisterStartMode(ModeApp); !errors.Is(err, ErrAlreadyRegistered) {
+		t.Errorf("RegisterStartMode() error = %v, want %v", err, ErrAlreadyRegistered)
+	}
+	if err := UnregisterStartMode(ModeApp); !errors.Is(err, ErrStandardValue) {
+		t.Errorf("UnregisterStartMode() error = %v, want %v", err, ErrStandardValue)
+	}
+	if err := RegisterStartMode(""); !errors.Is(err, ErrEmptyValue) {
+		t.Errorf("RegisterStartMode() error = %v, want %v", err, ErrEmptyValue)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	tests := []struct {
+		name       string
+		register   func() error
+		unregister func() error
+		valid      func() bool
+	}{
+		{
+			name:       "finish method",
+			register:   func() error { return RegisterFinishMethod("example_poll") },
+			unregister: func() error { return UnregisterFinishMethod("example_poll") },
+			valid:      FinishMethod("example_poll").Valid,
+		},
+		{
+			name:       "proof method",
+			register:   func() error { return RegisterProofMethod("example_dpop") },
+			unregister: func() error { return UnregisterProofMethod("example_dpop") },
+			valid:      ProofMethod("example_dpop").Valid,
+		},
+		{
+			name:       "key format",
+			register:   func() error { return RegisterKeyFormat("example_cose") },
+			unregister: func() error { return UnregisterKeyFormat("example_cose") },
+			valid:      KeyFormat("example_cose").Valid,
+		},
+		{
+			name:       "signature algorithm",
+			register:   func() error { return RegisterHTTPSigAlg("example-sig") },
+			unregister: func() error { return UnregisterHTTPSigAlg("example-sig") },
+			valid:      HTTPSigAlg("example-sig").Valid,
+		},
+		{
+			name:       "digest algorithm",
+			register:   func() error { return RegisterDigestAlg("example-digest") },
+			unregister: func() error { return UnregisterDigestAlg("example-digest") },
+			valid:      DigestAlg("example-digest").Valid,
+		},
+		{
+			name:       "token flag",
+			register:   func() error { return RegisterTokenFlag("example_flag") },
+			unregister: func() error { return UnregisterTokenFlag("example_flag") },
+			valid:      TokenFlag("example_flag").Valid,
+		},
+		{
+			name:       "assertion format",
+			register:   func() error { return RegisterAssertionFormat("example_vc") },
+			unregister: func() error { return UnregisterAssertionFormat("example_vc") },
+			valid:      AssertionFormat("example_vc").Valid,
+		},
+		{
+			name: "hash method",
+			register: func() error {
+				return RegisterHashMethod("example-sha-1", func(data []byte) []byte { x := sha1.Sum(data); return x[:] })
+			},
+			unregister: func() error { return UnregisterHashMethod("example-sha-1") },
+			valid:      HashMethod("example-sha-1").Valid,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.valid() {
+				t.Fatalf("Valid() = true before registration")
+			}
+			if err := tt.register(); err != nil {
+				t.Fatalf("Register() error = %v", err)
+			}
+			if !tt.valid() {
+				t.Errorf("Valid() = false after registration")
+			}
+			if err := tt.unregister(); err != nil {
+				t.Errorf("Unregister() error = %v", err)
+			}
+			if tt.valid() {
+				t.Errorf("Valid() = true after unregistration")
+			}
+		})
+	}
+}
+
+func TestRegisterHashMethod(t *testing.T) {
+	if err := RegisterHashMethod("example-nil", nil); !errors.Is(err, ErrInvalidHashMethod) {
+		t.Errorf("RegisterHashMethod() error = %v, want %v", err, ErrInvalidHashMethod)
+	}
+	const hm HashMethod = "example-identity"
+	_ = RegisterHashMethod(hm, func(data []byte) []byte { return data })
+	defer UnregisterHashMethod(hm)
+	if got := string(hm.Sum([]byte("abc"))); got != "abc" {
+		t.Errorf("HashMethod.Sum() = %v, want abc", got)
+	}
+}
@@ -0,0 +1,136 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/bingxueshuang/gnap/internal/registry"
+	"golang.org/x/exp/slices"
+)
+
+// AccessSchema declares how the fields of [AccessRight] are used by one
+// API type. Empty lists place no restriction on the field.
+type AccessSchema struct {
+	Actions           []string // permitted actions
+	Datatypes         []string // permitted datatypes
+	Privileges        []string // permitted privileges
+	AbsoluteLocations bool     // locations must be absolute URLs
+	Required          []string // json names of fields that must be present
+}
+
+// schemas is the registry of access schemas keyed by type. No schema is
+// standard.
+var schemas = registry.New(map[string]AccessSchema{})
+
+// RegisterSchema registers the schema used by [ValidateAccess] to
+// validate access rights of the given type.
+func RegisterSchema(typ string, s AccessSchema) error {
+	return schemas.Register(typ, s)
+}
+
+// UnregisterSchema unregisters the schema of the given type.
+func UnregisterSchema(typ string) error {
+	return schemas.Unregister(typ)
+}
+
+// Validate checks the structure of the access right: an access right by
+// value must have a type. It returns a [GNAPError] with code
+// "invalid_request".
+func (r AccessRight) Validate() error {
+	if r.Ref == "" && r.Type == "" {
+		return GNAPError{Code: "invalid_request", Desc: "access right: missing type"}
+	}
+	return nil
+}
+
+// Validate checks the access right against the schema. It returns a
+// [GNAPError] with code "invalid_request" whose description lists the
+// offending fields. Access rights by reference are not checked.
+func (s AccessSchema) Validate(r AccessRight) error {
+	if r.Ref != "" {
+		return nil
+	}
+	bad := s.check(r)
+	if len(bad) == 0 {
+		return nil
+	}
+	return GNAPError{
+		Code: "invalid_request",
+		Desc: fmt.Sprintf("access right of type %q: invalid fields: %s", r.Type, strings.Join(bad, ", ")),
+	}
+}
+
+// ValidateAccess validates every access right of the list, and checks
+// it against the schema registered for its type. Types without a
+// registered schema are only checked for structure.
+func ValidateAccess(rights []AccessRight) error {
+	for _, r := range rights {
+		err := r.Validate()
+		if err != nil {
+			return err
+		}
+		s, ok := schemas.Lookup(r.Type)
+		if !ok {
+			continue
+		}
+		err = s.Validate(r)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// check returns the json names of the fields of r violating the schema.
+func (s AccessSchema) check(r AccessRight) (bad []string) {
+	present := map[string]bool{
+		"actions":    len(r.Actions) > 0,
+		"locations":  len(r.Locations) > 0,
+		"datatypes":  len(r.Datatypes) > 0,
+		"identifier": r.Identifier != "",
+		"privileges": len(r.Privileges) > 0,
+	}
+	invalid := map[string]bool{
+		"actions":    !permitted(s.Actions, r.Actions),
+		"locations":  s.AbsoluteLocations && !absoluteURLs(r.Locations),
+		"datatypes":  !permitted(s.Datatypes, r.Datatypes),
+		"privileges": !permitted(s.Privileges, r.Privileges),
+	}
+	for _, field := range s.Required {
+		if !present[field] {
+			invalid[field] = true
+		}
+	}
+	for _, field := range []string{"actions", "locations", "datatypes", "identifier", "privileges"} {
+		if invalid[field] {
+			bad = append(bad, field)
+		}
+	}
+	return bad
+}
+
+// permitted reports whether all values are in the allowed list.
+// An empty allowed list permits any value.
+func permitted(allowed, values []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if !slices.Contains(allowed, v) {
+			return false
+		}
+	}
+	return true
+}
+
+// absoluteURLs reports whether all locations are absolute URLs.
+func absoluteURLs(locations []string) bool {
+	for _, loc := range locations {
+		u, err := url.Parse(loc)
+		if err != nil || !u.IsAbs() || u.Host == "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestValidateAccess(t *testing.T) {
+	err := RegisterSchema("photo-api", AccessSchema{
+		Actions:           []string{"read", "write", "delete"},
+		Datatypes:         []string{"metadata", "images"},
+		AbsoluteLocations: true,
+		Required:          []string{"actions"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = UnregisterSchema("photo-api") })
+	if err = RegisterSchema("photo-api", AccessSchema{}); !errors.Is(err, ErrAlreadyRegistered) {
+		t.Errorf("RegisterSchema() error = %v, want %v", err, ErrAlreadyRegistered)
+	}
+	tests := []struct {
+		name    string
+		in      string
+		wantErr string
+	}{
+		{
+			name: "valid",
+			in:   `{"type":"photo-api","actions":["read"],"locations":["https://server.example.net/"],"datatypes":["images"]}`,
+		},
+		{
+			name: "reference",
+			in:   `"dolphin-metadata"`,
+		},
+		{
+			name: "unregistered",
+			in:   `{"type":"financial-transaction","actions":["withdraw"]}`,
+		},
+		{
+			name:    "missing type",
+			in:      `{"actions":["read"]}`,
+			wantErr: "invalid_request: access right: missing type",
+		},
+		{
+			name:    "fields",
+			in:      `{"type":"photo-api","actions":["fly"],"locations":["/photos"],"datatypes":["images"]}`,
+			wantErr: `invalid_request: access right of type "photo-api": invalid fields: actions, locations`,
+		},
+		{
+			name:    "required",
+			in:      `{"type":"photo-api"}`,
+			wantErr: `invalid_request: access right of type "photo-api": invalid fields: actions`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got AccessRight
+			err := json.Unmarshal([]byte(tt.in), &got)
+			if err != nil {
+				t.Fatalf("AccessRight.UnmarshalJSON() error = %v, want structural decoding", err)
+			}
+			err = ValidateAccess([]AccessRight{got})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateAccess() error = %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("ValidateAccess() error = %v, want %v", err, tt.wantErr)
+			}
+			if !errors.Is(err, ErrGInvalidRequest) {
+				t.Errorf("ValidateAccess() error = %v, want %v", err, ErrGInvalidRequest)
+			}
+		})
+	}
+	var req TokenRequest
+	err = json.Unmarshal([]byte(`{"access":[{"type":"photo-api","actions":["fly"]}]}`), &req)
+	if err != nil {
+		t.Fatalf("TokenRequest decode error = %v", err)
+	}
+	if err = ValidateAccess(req.Access); !errors.Is(err, ErrGInvalidRequest) {
+		t.Errorf("ValidateAccess() error = %v, want %v", err, ErrGInvalidRequest)
+	}
+}
+
+func TestAccessSchema_Validate(t *testing.T) {
+	s := AccessSchema{Privileges: []string{"admin"}, Required: []string{"identifier"}}
+	tests := []struct {
+		name    string
+		right   AccessRight
+		wantErr bool
+	}{
+		{name: "valid", right: AccessRight{Type: "financial-transaction", Identifier: "account-14-32-32-3", Privileges: []string{"admin"}}},
+		{name: "reference", right: AccessRight{Ref: "dolphin-metadata"}},
+		{name: "privilege", right: AccessRight{Type: "financial-transaction", Identifier: "account-14-32-32-3", Privileges: []string{"root"}}, wantErr: true},
+		{name: "required", right: AccessRight{Type: "financial-transaction"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.Validate(tt.right); (err != nil) != tt.wantErr {
+				t.Errorf("AccessSchema.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
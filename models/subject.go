@@ -5,6 +5,7 @@ import (
 	"errors"
 	"time"
 
+	"github.com/bingxueshuang/gnap/internal/registry"
 	"github.com/bingxueshuang/gnap/subject"
 )
 
@@ -12,7 +13,7 @@ import (
 // in the registry is encountered.
 var ErrInvalidAFormat = errors.New("invalid assertion format")
 
-// AssertionFormat is a valid assertion format as defined in the draft.
+// AssertionFormat is a valid assertion format as defined in RFC 9635.
 type AssertionFormat string
 
 // Registry of valid assertion formats.
@@ -21,49 +22,99 @@ const (
 	AFsaml2   AssertionFormat = "saml2"
 )
 
-// assertionFormatsRegistry provides quick lookup for valid or invalid check.
-var assertionFormatsRegistry = map[string]AssertionFormat{
-	"id_token": AFidToken,
-	"saml2":    AFsaml2,
+// assertionFormats is the registry of assertion formats.
+var assertionFormats = registry.New(map[AssertionFormat]struct{}{
+	AFidToken: {},
+	AFsaml2:   {},
+})
+
+// RegisterAssertionFormat registers an extension assertion format.
+func RegisterAssertionFormat(format AssertionFormat) error {
+	return assertionFormats.Register(format, struct{}{})
+}
+
+// UnregisterAssertionFormat unregisters an extension assertion format.
+func UnregisterAssertionFormat(format AssertionFormat) error {
+	return assertionFormats.Unregister(format)
+}
+
+// Valid reports whether the assertion format is registered.
+func (af AssertionFormat) Valid() bool {
+	return assertionFormats.Contains(af)
+}
+
+// Standard reports whether the assertion format is a standard value.
+func (af AssertionFormat) Standard() bool {
+	return assertionFormats.Standard(af)
 }
 
-// MarshalJSON implements [json.Marshaler] interface.
+// MarshalJSON implements the [json.Marshaler] interface.
 func (af AssertionFormat) MarshalJSON() ([]byte, error) {
-	_, ok := assertionFormatsRegistry[string(af)]
-	if !ok {
-		return nil, ErrInvalidAFormat
-	}
-	return json.Marshal(string(af))
+	return registry.Marshal(assertionFormats, af, ErrInvalidAFormat)
 }
 
-// UnmarshalJSON implements [json.Unmarshaler] interface.
+// UnmarshalJSON implements the [json.Unmarshaler] interface.
 func (af *AssertionFormat) UnmarshalJSON(data []byte) error {
-	var format string
-	err := json.Unmarshal(data, &format)
-	if err != nil {
-		return err
-	}
-	_, ok := assertionFormatsRegistry[format]
-	if ok {
-		*af = AssertionFormat(format)
-		return nil
-	}
-	return ErrInvalidAFormat
+	return registry.Unmarshal(assertionFormats, data, af, ErrInvalidAFormat)
 }
 
 // SubRequest describes the information about the RO that the client instance
 // is requesting to be returned directly in the response from the AS.
 type SubRequest struct {
-	SFormats []subject.Format  `json:"sub_id_formats,omitempty"`
-	AFormats []AssertionFormat `json:"assertion_formats,omitempty"`
-	SubIDs   []subject.ID      `json:"sub_ids,omitempty"`
+	SFormats   []subject.Format  `json:"sub_id_formats,omitempty"`
+	AFormats   []AssertionFormat `json:"assertion_formats,omitempty"`
+	SubIDs     []subject.ID      `json:"sub_ids,omitempty"`
+	Extensions Extensions        `json:"-"`
+}
+
+// MarshalJSON implements the [json.Marshaler] interface. Extension
+// members are encoded after the members of the model.
+func (req SubRequest) MarshalJSON() ([]byte, error) {
+	type Alias SubRequest
+	return marshalExtensions(Alias(req), req.Extensions)
+}
+
+// UnmarshalJSON implements the [json.Unmarshaler] interface. Unknown
+// members are kept in Extensions.
+func (req *SubRequest) UnmarshalJSON(data []byte) error {
+	type Alias SubRequest
+	var alias Alias
+	ext, err := unmarshalExtensions(data, &alias)
+	if err != nil {
+		return err
+	}
+	alias.Extensions = ext
+	*req = SubRequest(alias)
+	return nil
 }
 
 // SubResponse contains claims about the RO as known and declared by the AS.
 type SubResponse struct {
 	SubIDs     []subject.ID `json:"sub_ids,omitempty"`
 	Assertions []Assertion  `json:"assertions,omitempty"`
-	UpdatedAt  time.Time    `json:"updated_at,omitempty"`
+	UpdatedAt  *time.Time   `json:"updated_at,omitempty"`
+	Extensions Extensions   `json:"-"`
+}
+
+// MarshalJSON implements the [json.Marshaler] interface. Extension
+// members are encoded after the members of the model.
+func (res SubResponse) MarshalJSON() ([]byte, error) {
+	type Alias SubResponse
+	return marshalExtensions(Alias(res), res.Extensions)
+}
+
+// UnmarshalJSON implements the [json.Unmarshaler] interface. Unknown
+// members are kept in Extensions.
+func (res *SubResponse) UnmarshalJSON(data []byte) error {
+	type Alias SubResponse
+	var alias Alias
+	ext, err := unmarshalExtensions(data, &alias)
+	if err != nil {
+		return err
+	}
+	alias.Extensions = ext
+	*res = SubResponse(alias)
+	return nil
 }
 
 // EndUser identifies the end user to the AS in a manner that the AS can verify
@@ -107,3 +158,79 @@ type Assertion struct {
 	Value  string          `json:"value"`
 	Format AssertionFormat `json:"format"`
 }
+
+// validateSubIDs checks every subject identifier of the list.
+func validateSubIDs(ids []subject.ID) error {
+	for i, id := range ids {
+		err := id.Validate()
+		if err != nil {
+			return fieldError(index(i), err)
+		}
+	}
+	return nil
+}
+
+// validateAssertions checks every assertion of the list.
+func validateAssertions(assertions []Assertion) error {
+	for i, a := range assertions {
+		err := a.Validate()
+		if err != nil {
+			return fieldError(index(i), err)
+		}
+	}
+	return nil
+}
+
+// Validate checks the assertion format and presence of the value.
+func (a Assertion) Validate() error {
+	if !assertionFormats.Contains(a.Format) {
+		return fieldError("format", ErrInvalidAFormat)
+	}
+	if a.Value == "" {
+		return fieldError("value", ErrMissingField)
+	}
+	return nil
+}
+
+// Validate checks the subject information request.
+func (req SubRequest) Validate() error {
+	for i, format := range req.SFormats {
+		if !format.Valid() {
+			return fieldError("sub_id_formats"+index(i), subject.ErrInvalidFormat)
+		}
+	}
+	for i, format := range req.AFormats {
+		if !assertionFormats.Contains(format) {
+			return fieldError("assertion_formats"+index(i), ErrInvalidAFormat)
+		}
+	}
+	return fieldError("sub_ids", validateSubIDs(req.SubIDs))
+}
+
+// Validate checks the subject information returned by the AS.
+func (res SubResponse) Validate() error {
+	err := validateSubIDs(res.SubIDs)
+	if err != nil {
+		return fieldError("sub_ids", err)
+	}
+	return fieldError("assertions", validateAssertions(res.Assertions))
+}
+
+// Validate checks the end user information. An end user by value must
+// be identified by a subject identifier or an assertion.
+func (u EndUser) Validate() error {
+	if u.Ref != "" {
+		if len(u.SubIDs) > 0 || len(u.Assertions) > 0 {
+			return ErrConflictingFields
+		}
+		return nil
+	}
+	if len(u.SubIDs) == 0 && len(u.Assertions) == 0 {
+		return fieldError("sub_ids", ErrMissingField)
+	}
+	err := validateSubIDs(u.SubIDs)
+	if err != nil {
+		return fieldError("sub_ids", err)
+	}
+	return fieldError("assertions", validateAssertions(u.Assertions))
+}
@@ -3,7 +3,9 @@ package models
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 
+	"github.com/bingxueshuang/gnap/internal/registry"
 	"golang.org/x/exp/slices"
 )
 
@@ -20,38 +22,75 @@ const (
 	FlagDurable TokenFlag = "durable"
 )
 
-// flagRegistry is a quick mapping from allowed values to token flags.
-var flagRegistry = map[string]TokenFlag{
-	"bearer":  FlagBearer,
-	"durable": FlagDurable,
+// tokenFlags is the registry of token flags.
+var tokenFlags = registry.New(map[TokenFlag]struct{}{
+	FlagBearer:  {},
+	FlagDurable: {},
+})
+
+// RegisterTokenFlag registers an extension token flag.
+func RegisterTokenFlag(flag TokenFlag) error {
+	return tokenFlags.Register(flag, struct{}{})
+}
+
+// UnregisterTokenFlag unregisters an extension token flag.
+func UnregisterTokenFlag(flag TokenFlag) error {
+	return tokenFlags.Unregister(flag)
+}
+
+// Valid reports whether the token flag is registered.
+func (tf TokenFlag) Valid() bool {
+	return tokenFlags.Contains(tf)
+}
+
+// Standard reports whether the token flag is a standard value.
+func (tf TokenFlag) Standard() bool {
+	return tokenFlags.Standard(tf)
 }
 
 // MarshalJSON implements the [json.Marshaler] interface.
 func (tf TokenFlag) MarshalJSON() ([]byte, error) {
-	_, ok := flagRegistry[string(tf)]
-	if ok {
-		return json.Marshal(string(tf))
-	}
-	return nil, ErrInvalidTokenFlag
+	return registry.Marshal(tokenFlags, tf, ErrInvalidTokenFlag)
 }
 
 // UnmarshalJSON implements the [json.Unmarshaler] interface.
 func (tf *TokenFlag) UnmarshalJSON(data []byte) error {
-	var flag string
-	err := json.Unmarshal(data, &flag)
-	if err == nil {
-		*tf = TokenFlag(flag)
-		return nil
-	}
-	return ErrInvalidTokenFlag
+	return registry.Unmarshal(tokenFlags, data, tf, ErrInvalidTokenFlag)
+}
+
+// hasFlag reports whether flag is one of flags.
+func hasFlag(flags []TokenFlag, flag TokenFlag) bool {
+	return slices.Contains(flags, flag)
 }
 
 // TokenRequest represents access token request object for requesting
 // access to resources.
 type TokenRequest struct {
-	Access []AccessRight `json:"access"`
-	Label  string        `json:"label,omitempty"`
-	Flags  []TokenFlag   `json:"flags,omitempty"`
+	Access     []AccessRight `json:"access"`
+	Label      string        `json:"label,omitempty"`
+	Flags      []TokenFlag   `json:"flags,omitempty"`
+	Extensions Extensions    `json:"-"`
+}
+
+// MarshalJSON implements the [json.Marshaler] interface. Extension
+// members are encoded after the members of the model.
+func (req TokenRequest) MarshalJSON() ([]byte, error) {
+	type Alias TokenRequest
+	return marshalExtensions(Alias(req), req.Extensions)
+}
+
+// UnmarshalJSON implements the [json.Unmarshaler] interface. Unknown
+// members are kept in Extensions.
+func (req *TokenRequest) UnmarshalJSON(data []byte) error {
+	type Alias TokenRequest
+	var alias Alias
+	ext, err := unmarshalExtensions(data, &alias)
+	if err != nil {
+		return err
+	}
+	alias.Extensions = ext
+	*req = TokenRequest(alias)
+	return nil
 }
 
 // NewTokenRequest is a constructor for TokenRequest.
@@ -71,13 +110,35 @@ type tokenRequestOption func(*TokenRequest) error
 
 // TokenResponse represents the access token granted by the AS.
 type TokenResponse struct {
-	Value     string        `json:"value"`
-	Label     string        `json:"label,omitempty"`
-	Manage    URL           `json:"manage,omitempty"`
-	Access    []AccessRight `json:"access"`
-	ExpiresIn int           `json:"expires_in,omitempty"`
-	Key       ClientKey     `json:"key,omitempty"`
-	Flags     []TokenFlag   `json:"flags,omitempty"`
+	Value      string        `json:"value"`
+	Label      string        `json:"label,omitempty"`
+	Manage     *TokenManage  `json:"manage,omitempty"`
+	Access     []AccessRight `json:"access"`
+	ExpiresIn  int           `json:"expires_in,omitempty"`
+	Key        *ClientKey    `json:"key,omitempty"`
+	Flags      []TokenFlag   `json:"flags,omitempty"`
+	Extensions Extensions    `json:"-"`
+}
+
+// MarshalJSON implements the [json.Marshaler] interface. Extension
+// members are encoded after the members of the model.
+func (res TokenResponse) MarshalJSON() ([]byte, error) {
+	type Alias TokenResponse
+	return marshalExtensions(Alias(res), res.Extensions)
+}
+
+// UnmarshalJSON implements the [json.Unmarshaler] interface. Unknown
+// members are kept in Extensions.
+func (res *TokenResponse) UnmarshalJSON(data []byte) error {
+	type Alias TokenResponse
+	var alias Alias
+	ext, err := unmarshalExtensions(data, &alias)
+	if err != nil {
+		return err
+	}
+	alias.Extensions = ext
+	*res = TokenResponse(alias)
+	return nil
 }
 
 // NewTokenResponse is constructor for TokenResponse.
@@ -96,15 +157,53 @@ func NewTokenResponse(value string, access []AccessRight, options ...tokenRespon
 type tokenResponseOption func(*TokenResponse) error
 
 // ContinueToken represents continuation access token to
-// be presented for continuation request.
+// be presented for continuation request. The same form is used for
+// the access token of the token management API.
 type ContinueToken struct {
 	Value     string      `json:"value"`
 	Label     string      `json:"label,omitempty"`
-	Manage    URL         `json:"manage,omitempty"`
 	ExpiresIn int         `json:"expires_in,omitempty"`
 	Flags     []TokenFlag `json:"flags,omitempty"`
 }
 
+// TokenManage represents the access information for the token
+// management API of an access token.
+type TokenManage struct {
+	URI   URL           `json:"uri"`
+	Token ContinueToken `json:"access_token"`
+}
+
+// MarshalJSON implements the [json.Marshaler] interface. A management
+// object without an access token, as decoded from a draft-13 message,
+// is encoded back as the bare management URI.
+func (m TokenManage) MarshalJSON() ([]byte, error) {
+	if m.Token.Value == "" {
+		return json.Marshal(m.URI)
+	}
+	type Alias TokenManage
+	return json.Marshal(Alias(m))
+}
+
+// UnmarshalJSON implements the [json.Unmarshaler] interface. For
+// compatibility with draft-13 peers, the management URI given as a
+// bare string is also accepted, in which case Token is left empty.
+func (m *TokenManage) UnmarshalJSON(data []byte) error {
+	var uri string
+	err := json.Unmarshal(data, &uri)
+	if err == nil { // draft-13 management URI
+		*m = TokenManage{}
+		return json.Unmarshal(data, &m.URI)
+	}
+	type Alias TokenManage
+	var obj Alias
+	err = json.Unmarshal(data, &obj)
+	if err != nil {
+		return err
+	}
+	*m = TokenManage(obj)
+	return nil
+}
+
 // WithLabel is optional parameter for [NewTokenRequest]
 // to request a label for the token.
 func WithLabel(label string) tokenRequestOption {
@@ -137,10 +236,10 @@ func WithLabelResponse(label string) tokenResponseOption {
 }
 
 // WithManage is optional parameter for [NewTokenResponse]
-// to provide the token management URI.
-func WithManage(manage URL) tokenResponseOption {
+// to provide the token management URI and its access token.
+func WithManage(manage TokenManage) tokenResponseOption {
 	return func(res *TokenResponse) error {
-		res.Manage = manage
+		res.Manage = &manage
 		return nil
 	}
 }
@@ -158,7 +257,7 @@ func WithExpiry(seconds int) tokenResponseOption {
 // to provide the key to be presented with the access token.
 func WithKey(key ClientKey) tokenResponseOption {
 	return func(res *TokenResponse) error {
-		res.Key = key
+		res.Key = &key
 		return nil
 	}
 }
@@ -171,3 +270,131 @@ func WithFlags(flags []TokenFlag) tokenResponseOption {
 		return nil
 	}
 }
+
+// flagError wraps err with the gnap error "invalid_flag", so that the
+// AS can respond with the error code.
+func flagError(err error, desc string) error {
+	return fmt.Errorf("%w: %w", err, GNAPError{Code: "invalid_flag", Desc: desc})
+}
+
+// validateFlags checks that all flags are defined in the registry and
+// are not repeated.
+func validateFlags(flags []TokenFlag) error {
+	for i, flag := range flags {
+		if !tokenFlags.Contains(flag) {
+			return fieldError(index(i), flagError(ErrInvalidTokenFlag, fmt.Sprintf("flag %q is not registered", flag)))
+		}
+		if slices.Index(flags, flag) != i {
+			return fieldError(index(i), flagError(ErrConflictingFields, fmt.Sprintf("flag %q is repeated", flag)))
+		}
+	}
+	return nil
+}
+
+// Bearer reports whether a bearer token is requested.
+func (req TokenRequest) Bearer() bool {
+	return hasFlag(req.Flags, FlagBearer)
+}
+
+// Bearer reports whether the access token is a bearer token, which is
+// presented without proof of possession of a key.
+func (res TokenResponse) Bearer() bool {
+	return hasFlag(res.Flags, FlagBearer)
+}
+
+// Durable reports whether the access token remains valid after it is
+// rotated or its grant is modified.
+func (res TokenResponse) Durable() bool {
+	return hasFlag(res.Flags, FlagDurable)
+}
+
+// Validate checks the token request against the rules of RFC 9635.
+func (req TokenRequest) Validate() error {
+	if len(req.Access) == 0 {
+		return fieldError("access", ErrMissingField)
+	}
+	for i, right := range req.Access {
+		err := right.Validate()
+		if err != nil {
+			return fieldError("access"+index(i), err)
+		}
+	}
+	err := validateFlags(req.Flags)
+	if err != nil {
+		return fieldError("flags", err)
+	}
+	if i := slices.Index(req.Flags, FlagDurable); i >= 0 {
+		return fieldError("flags"+index(i), flagError(ErrInvalidValue, "flag \"durable\" is only set by the AS"))
+	}
+	return nil
+}
+
+// Validate checks the token response against the rules of RFC 9635.
+// Violations of the flag semantics, such as a bearer token bound to a
+// key, wrap the gnap error "invalid_flag".
+func (res TokenResponse) Validate() error {
+	if res.Value == "" {
+		return fieldError("value", ErrMissingField)
+	}
+	if res.Manage != nil {
+		err := res.Manage.Validate()
+		if err != nil {
+			return fieldError("manage", err)
+		}
+	}
+	if len(res.Access) == 0 {
+		return fieldError("access", ErrMissingField)
+	}
+	for i, right := range res.Access {
+		err := right.Validate()
+		if err != nil {
+			return fieldError("access"+index(i), err)
+		}
+	}
+	if res.ExpiresIn < 0 {
+		return fieldError("expires_in", ErrInvalidValue)
+	}
+	if res.Key != nil {
+		err := res.Key.Validate()
+		if err != nil {
+			return fieldError("key", err)
+		}
+	}
+	err := validateFlags(res.Flags)
+	if err != nil {
+		return fieldError("flags", err)
+	}
+	if res.Bearer() && res.Key != nil {
+		return fieldError("key", flagError(ErrConflictingFields, "bearer token must not be bound to a key"))
+	}
+	return nil
+}
+
+// Validate checks the continuation access token, which must not be
+// a bearer token.
+func (t ContinueToken) Validate() error {
+	if t.Value == "" {
+		return fieldError("value", ErrMissingField)
+	}
+	if t.ExpiresIn < 0 {
+		return fieldError("expires_in", ErrInvalidValue)
+	}
+	err := validateFlags(t.Flags)
+	if err != nil {
+		return fieldError("flags", err)
+	}
+	if i := slices.Index(t.Flags, FlagBearer); i >= 0 {
+		return fieldError("flags"+index(i), flagError(ErrConflictingFields, "token must be bound to the client key"))
+	}
+	return nil
+}
+
+// Validate checks the token management information. The management
+// access token follows the rules of the continuation access token.
+func (m TokenManage) Validate() error {
+	err := validateURL(&m.URI)
+	if err != nil {
+		return fieldError("uri", err)
+	}
+	return fieldError("access_token", m.Token.Validate())
+}
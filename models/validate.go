@@ -0,0 +1,65 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Errors returned by the Validate methods, wrapped in a [FieldError].
+var (
+	ErrMissingField      = errors.New("missing required field")
+	ErrInvalidValue      = errors.New("invalid field value")
+	ErrConflictingFields = errors.New("conflicting fields")
+	ErrEmptyResponse     = errors.New("empty response")
+)
+
+// FieldError identifies the field of a model that failed validation by
+// its json path, such as "interact.finish.uri" or "access_token[1].label".
+type FieldError struct {
+	Path string
+	Err  error
+}
+
+// Error implements error interface.
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// fieldError wraps err with the json path of the field. If err already
+// is a [FieldError] of a nested field, path is prepended to its path.
+func fieldError(path string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var ferr *FieldError
+	if errors.As(err, &ferr) {
+		sep := "."
+		if strings.HasPrefix(ferr.Path, "[") {
+			sep = ""
+		}
+		return &FieldError{Path: path + sep + ferr.Path, Err: ferr.Err}
+	}
+	return &FieldError{Path: path, Err: err}
+}
+
+// index formats the json path of the i-th element of an array.
+func index(i int) string {
+	return fmt.Sprintf("[%d]", i)
+}
+
+// validateURL checks that the url is present and absolute.
+func validateURL(u *URL) error {
+	if u == nil || u.URL == nil || u.String() == "" {
+		return ErrMissingField
+	}
+	if !u.IsAbs() {
+		return ErrInvalidURL
+	}
+	return nil
+}
@@ -0,0 +1,278 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestGrantRequest_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       string
+		wantPath string
+		wantErr  error
+	}{
+		{
+			name: "valid",
+			in: `{
+				"access_token": {"access": [{"type": "photo-api", "actions": ["read"]}, "dolphin-metadata"]},
+				"client": {"key": {"proof": "httpsig", "jwk": {"kty": "RSA", "e": "AQAB", "kid": "xyz-1", "alg": "RS256", "n": "kOB5rR4Jv0GMeL"}}},
+				"interact": {
+					"start": ["redirect"],
+					"finish": {"method": "redirect", "uri": "https://client.example.net/return/123455", "nonce": "LKLTI25DK82FX4T4QFZC"}
+				}
+			}`,
+		},
+		{
+			name:     "finish without start",
+			in:       `{"client": "7C7C4AZ9KHRS6X63AJAO", "interact": {"finish": {"method": "push", "uri": "https://client.example.net/push", "nonce": "LKLTI25DK82FX4T4QFZC"}}}`,
+			wantPath: "interact.start",
+			wantErr:  ErrMissingField,
+		},
+		{
+			name:     "user code with redirect",
+			in:       `{"client": "7C7C4AZ9KHRS6X63AJAO", "interact": {"start": ["user_code"], "finish": {"method": "redirect", "uri": "https://client.example.net/return", "nonce": "LKLTI25DK82FX4T4QFZC"}}}`,
+			wantPath: "interact.finish.method",
+			wantErr:  ErrConflictingFields,
+		},
+		{
+			name:     "relative finish uri",
+			in:       `{"client": "7C7C4AZ9KHRS6X63AJAO", "interact": {"start": ["redirect"], "finish": {"method": "redirect", "uri": "/return", "nonce": "LKLTI25DK82FX4T4QFZC"}}}`,
+			wantPath: "interact.finish.uri",
+			wantErr:  ErrInvalidURL,
+		},
+		{
+			name:     "multiple without label",
+			in:       `{"client": "7C7C4AZ9KHRS6X63AJAO", "access_token": [{"label": "a", "access": ["x"]}, {"access": ["y"]}]}`,
+			wantPath: "access_token[1].label",
+			wantErr:  ErrInvalidTokenRequest,
+		},
+		{
+			name:     "duplicate label",
+			in:       `{"client": "7C7C4AZ9KHRS6X63AJAO", "access_token": [{"label": "a", "access": ["x"]}, {"label": "a", "access": ["y"]}]}`,
+			wantPath: "access_token[1].label",
+			wantErr:  ErrInvalidTokenRequest,
+		},
+		{
+			name:     "missing access",
+			in:       `{"client": "7C7C4AZ9KHRS6X63AJAO", "access_token": {"access": [], "label": "a"}}`,
+			wantPath: "access_token.access",
+			wantErr:  ErrMissingField,
+		},
+		{
+			name:     "key without proof",
+			in:       `{"client": {"key": {"proof": "httpsig"}}}`,
+			wantPath: "client.key.jwk",
+			wantErr:  ErrMissingField,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req GrantRequest
+			err := json.Unmarshal([]byte(tt.in), &req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkFieldError(t, req.Validate(), tt.wantPath, tt.wantErr)
+		})
+	}
+}
+
+func TestGrantResponse_Validate(t *testing.T) {
+	continueURI, _ := ParseURL("https://server.example.com/continue")
+	redirect, _ := ParseURL("https://server.example.com/interact/4CF492MLVMSW9MKMXKHQ")
+	con := ContinueResponse{URI: continueURI, Wait: 30, Token: ContinueToken{Value: "80UPRY5NM33OMUKMKSKU"}}
+	tests := []struct {
+		name     string
+		in       GrantResponse
+		wantPath string
+		wantErr  error
+	}{
+		{
+			name: "interact",
+			in:   GrantResponse{Continue: &con, Interact: &IAResponse{Redirect: &redirect}},
+		},
+		{
+			name:    "empty",
+			wantErr: ErrEmptyResponse,
+		},
+		{
+			name:     "interact without continue",
+			in:       GrantResponse{Interact: &IAResponse{Redirect: &redirect}},
+			wantPath: "continue",
+			wantErr:  ErrMissingField,
+		},
+		{
+			name: "bearer continuation",
+			in: GrantResponse{Continue: &ContinueResponse{
+				URI:   continueURI,
+				Token: ContinueToken{Value: "80UPRY5NM33OMUKMKSKU", Flags: []TokenFlag{FlagBearer}},
+			}},
+			wantPath: "continue.access_token.flags[0]",
+			wantErr:  ErrConflictingFields,
+		},
+		{
+			name: "error with token",
+			in: GrantResponse{
+				Error:       &GNAPError{Code: "user_denied"},
+				AccessToken: &ATResponse{Single: TokenResponse{Value: "x", Access: []AccessRight{{Ref: "a"}}}},
+			},
+			wantPath: "error",
+			wantErr:  ErrConflictingFields,
+		},
+		{
+			name: "manage without token",
+			in: GrantResponse{AccessToken: &ATResponse{Single: TokenResponse{
+				Value:  "x",
+				Manage: &TokenManage{URI: continueURI},
+				Access: []AccessRight{{Ref: "a"}},
+			}}},
+			wantPath: "access_token.manage.access_token.value",
+			wantErr:  ErrMissingField,
+		},
+		{
+			name: "multiple duplicate label",
+			in: GrantResponse{AccessToken: &ATResponse{Multiple: []TokenResponse{
+				{Value: "x", Label: "a", Access: []AccessRight{{Ref: "a"}}},
+				{Value: "y", Label: "a", Access: []AccessRight{{Ref: "b"}}},
+			}}},
+			wantPath: "access_token[1].label",
+			wantErr:  ErrInvalidTokenResponse,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkFieldError(t, tt.in.Validate(), tt.wantPath, tt.wantErr)
+		})
+	}
+}
+
+// checkFieldError checks that err wraps want and, if path is not empty,
+// that it is a FieldError with that path.
+func checkFieldError(t *testing.T, err error, path string, want error) {
+	t.Helper()
+	if want == nil {
+		if err != nil {
+			t.Errorf("Validate() error = %v, want nil", err)
+		}
+		return
+	}
+	if !errors.Is(err, want) {
+		t.Errorf("Validate() error = %v, want %v", err, want)
+		return
+	}
+	if path == "" {
+		return
+	}
+	var ferr *FieldError
+	if !errors.As(err, &ferr) || ferr.Path != path {
+		t.Errorf("Validate() error = %v, want path %v", err, path)
+	}
+}
+
+func TestTokenResponse_Validate(t *testing.T) {
+	key := ClientKey{Proof: ProofJWSD, JWK: json.RawMessage(`{"kty":"OKP","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`)}
+	access := []AccessRight{{Ref: "dolphin-metadata"}}
+	tests := []struct {
+		name     string
+		in       TokenResponse
+		wantPath string
+		wantErr  error
+	}{
+		{
+			name: "bearer",
+			in:   TokenResponse{Value: "x", Access: access, Flags: []TokenFlag{FlagBearer, FlagDurable}},
+		},
+		{
+			name: "bound",
+			in:   TokenResponse{Value: "x", Access: access, Key: &key},
+		},
+		{
+			name:     "bearer with key",
+			in:       TokenResponse{Value: "x", Access: access, Key: &key, Flags: []TokenFlag{FlagBearer}},
+			wantPath: "key",
+			wantErr:  ErrGInvalidFlag,
+		},
+		{
+			name:     "unregistered flag",
+			in:       TokenResponse{Value: "x", Access: access, Flags: []TokenFlag{"example_flag"}},
+			wantPath: "flags[0]",
+			wantErr:  ErrGInvalidFlag,
+		},
+		{
+			name:     "repeated flag",
+			in:       TokenResponse{Value: "x", Access: access, Flags: []TokenFlag{FlagDurable, FlagDurable}},
+			wantPath: "flags[1]",
+			wantErr:  ErrConflictingFields,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.in.Validate()
+			checkFieldError(t, err, tt.wantPath, tt.wantErr)
+			var gerr GNAPError
+			if tt.wantErr != nil && (!errors.As(err, &gerr) || gerr.Code != "invalid_flag") {
+				t.Errorf("Validate() error = %v, want gnap error invalid_flag", err)
+			}
+		})
+	}
+}
+
+func TestTokenRequest_Validate(t *testing.T) {
+	access := []AccessRight{{Ref: "dolphin-metadata"}}
+	tests := []struct {
+		name     string
+		in       TokenRequest
+		wantPath string
+		wantErr  error
+	}{
+		{
+			name: "bearer",
+			in:   TokenRequest{Access: access, Flags: []TokenFlag{FlagBearer}},
+		},
+		{
+			name:     "durable",
+			in:       TokenRequest{Access: access, Flags: []TokenFlag{FlagBearer, FlagDurable}},
+			wantPath: "flags[1]",
+			wantErr:  ErrGInvalidFlag,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkFieldError(t, tt.in.Validate(), tt.wantPath, tt.wantErr)
+		})
+	}
+}
+
+func TestIntrospectRequest_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      IntrospectRequest
+		wantPath string
+		wantErr  error
+	}{
+		{name: "valid", req: IntrospectRequest{AccessToken: "OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0", Proof: ProofHTTPSig, ResourceServer: RSIdentity{Ref: "7C7C4AZ9KHRS6X63AJAO"}}},
+		{name: "missing token", req: IntrospectRequest{ResourceServer: RSIdentity{Ref: "7C7C4AZ9KHRS6X63AJAO"}}, wantPath: "access_token", wantErr: ErrMissingField},
+		{name: "unknown proof", req: IntrospectRequest{AccessToken: "OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0", Proof: "unknown", ResourceServer: RSIdentity{Ref: "7C7C4AZ9KHRS6X63AJAO"}}, wantPath: "proof", wantErr: ErrInvalidProofMethod},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			checkFieldError(t, err, tt.wantPath, tt.wantErr)
+			if (err != nil) != errors.Is(err, ErrInvalidIntrospection) {
+				t.Errorf("Validate() error = %v, want %v", err, ErrInvalidIntrospection)
+			}
+		})
+	}
+}
+
+func TestTokenFlag_UnmarshalJSON(t *testing.T) {
+	var flags []TokenFlag
+	if err := json.Unmarshal([]byte(`["bearer","durable"]`), &flags); err != nil {
+		t.Errorf("json.Unmarshal() error = %v", err)
+	}
+	if err := json.Unmarshal([]byte(`["bearer","example_flag"]`), &flags); !errors.Is(err, ErrInvalidTokenFlag) {
+		t.Errorf("json.Unmarshal() error = %v, want %v", err, ErrInvalidTokenFlag)
+	}
+}
@@ -0,0 +1,7 @@
+// Package nonce generates the unguessable values used in GNAP, such as
+// interaction nonces, interaction references, continuation tokens and
+// access tokens. Values are drawn from [crypto/rand] by default; tests
+// may give a generator a deterministic source with [WithReader], and
+// install it in the AS and the client with their SetNonceGenerator
+// functions.
+package nonce // import "github.com/bingxueshuang/gnap/nonce"
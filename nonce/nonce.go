@@ -0,0 +1,168 @@
+package nonce
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Alphabets of generated values.
+const (
+	// Alphanumeric is made of upper case letters and digits, as in the
+	// examples of RFC 9635. It is the default alphabet.
+	Alphanumeric = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	// URLSafe is the alphabet of base64url encoding.
+	URLSafe = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+)
+
+// DefaultLength is the default number of characters of generated values.
+const DefaultLength = 20
+
+// minBits is the least entropy accepted for a generator.
+const minBits = 64
+
+// ErrWeakGenerator is returned when the length and alphabet of a
+// generator provide less than 64 bits of entropy.
+var ErrWeakGenerator = errors.New("generator too weak")
+
+// ErrRejectedSource is returned when the source of randomness yields too
+// few bytes usable for the alphabet, as a broken source would.
+var ErrRejectedSource = errors.New("random source rejected")
+
+// maxReads is the number of reads of the source after which a generator
+// gives up. A uniform source needs more than a few reads with
+// negligible probability.
+const maxReads = 16
+
+// Generator generates random strings of fixed length over an alphabet.
+// It is safe for concurrent use.
+type Generator struct {
+	length   int
+	alphabet string
+	reader   io.Reader
+}
+
+// New is the constructor for [Generator] with optional parameters.
+// By default, values are [DefaultLength] characters of [Alphanumeric]
+// drawn from [crypto/rand].
+func New(options ...generatorOption) (g *Generator, err error) {
+	g = &Generator{length: DefaultLength, alphabet: Alphanumeric, reader: rand.Reader}
+	for _, setter := range options {
+		err = setter(g)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if g.bits() < minBits {
+		return nil, fmt.Errorf("%w: %d characters of %d symbols", ErrWeakGenerator, g.length, len(g.alphabet))
+	}
+	return g, nil
+}
+
+// MustNew is like [New] but panics on error. It simplifies the
+// initialization of global generators.
+func MustNew(options ...generatorOption) *Generator {
+	g, err := New(options...)
+	if err != nil {
+		panic(err)
+	}
+	return g
+}
+
+// generatorOption is functional parameter for generator constructor.
+type generatorOption func(*Generator) error
+
+// WithLength is an optional parameter for [New] to set the number of
+// characters of generated values.
+func WithLength(n int) generatorOption {
+	return func(g *Generator) error {
+		if n <= 0 {
+			return fmt.Errorf("invalid length: %d", n)
+		}
+		g.length = n
+		return nil
+	}
+}
+
+// WithAlphabet is an optional parameter for [New] to set the characters
+// generated values are made of. It must have between 2 and 256 distinct
+// single byte characters.
+func WithAlphabet(alphabet string) generatorOption {
+	return func(g *Generator) error {
+		if len(alphabet) < 2 || len(alphabet) > 256 {
+			return fmt.Errorf("invalid alphabet size: %d", len(alphabet))
+		}
+		var seen [256]bool
+		for i := 0; i < len(alphabet); i++ {
+			if seen[alphabet[i]] {
+				return fmt.Errorf("repeated character in alphabet: %q", alphabet[i])
+			}
+			seen[alphabet[i]] = true
+		}
+		g.alphabet = alphabet
+		return nil
+	}
+}
+
+// WithReader is an optional parameter for [New] to set the source of
+// randomness, so that tests get deterministic output. It must not be
+// used outside of tests. Reads from the source are not synchronized.
+func WithReader(r io.Reader) generatorOption {
+	return func(g *Generator) error {
+		if r == nil {
+			return errors.New("missing reader")
+		}
+		g.reader = r
+		return nil
+	}
+}
+
+// bits estimates the entropy of generated values.
+func (g *Generator) bits() int {
+	size := len(g.alphabet)
+	perChar := 0
+	for 1<<(perChar+1) <= size {
+		perChar++
+	}
+	return perChar * g.length
+}
+
+// Generate returns a new random value. Characters are drawn uniformly
+// from the alphabet, by rejecting the random bytes that would bias them.
+// It fails with [ErrRejectedSource] if the value is not complete after
+// a bounded number of reads.
+func (g *Generator) Generate() (string, error) {
+	size := len(g.alphabet)
+	limit := 256 - 256%size
+	out := make([]byte, 0, g.length)
+	buf := make([]byte, g.length)
+	for reads := 0; len(out) < g.length; reads++ {
+		if reads == maxReads {
+			return "", ErrRejectedSource
+		}
+		_, err := io.ReadFull(g.reader, buf)
+		if err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			if int(b) >= limit {
+				continue
+			}
+			out = append(out, g.alphabet[int(b)%size])
+			if len(out) == g.length {
+				break
+			}
+		}
+	}
+	return string(out), nil
+}
+
+// defaultGenerator generates values with the default parameters.
+var defaultGenerator = MustNew()
+
+// Generate returns a new random value of [DefaultLength] characters of
+// [Alphanumeric].
+func Generate() (string, error) {
+	return defaultGenerator.Generate()
+}
@@ -0,0 +1,107 @@
+package nonce
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestGenerator_Generate(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []generatorOption
+		random  []byte
+		want    string
+	}{
+		{
+			name:   "default",
+			random: bytes.Repeat([]byte{0, 1, 35, 36}, 5),
+			want:   "AB9AAB9AAB9AAB9AAB9A",
+		},
+		{
+			name:    "rejects biased bytes",
+			options: []generatorOption{WithLength(64), WithAlphabet("abc")},
+			random:  append(bytes.Repeat([]byte{255}, 64), bytes.Repeat([]byte{1}, 64)...),
+			want:    strings.Repeat("b", 64),
+		},
+		{
+			name:    "url safe",
+			options: []generatorOption{WithLength(11), WithAlphabet(URLSafe)},
+			random:  []byte{0, 25, 26, 51, 52, 61, 62, 63, 64, 127, 255},
+			want:    "AZaz09-_A__",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := New(append(tt.options, WithReader(bytes.NewReader(tt.random)))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := g.Generate()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Generator.Generate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerator_Generate_rejected(t *testing.T) {
+	g, err := New(WithAlphabet("abc"), WithLength(64), WithReader(constReader(255)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = g.Generate(); !errors.Is(err, ErrRejectedSource) {
+		t.Errorf("Generator.Generate() error = %v, want %v", err, ErrRejectedSource)
+	}
+}
+
+// constReader is an endless source of the same byte.
+type constReader byte
+
+func (r constReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = byte(r)
+	}
+	return len(p), nil
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []generatorOption
+		wantErr bool
+	}{
+		{name: "default"},
+		{name: "weak", options: []generatorOption{WithLength(8)}, wantErr: true},
+		{name: "repeated character", options: []generatorOption{WithAlphabet("aab")}, wantErr: true},
+		{name: "single character", options: []generatorOption{WithAlphabet("a")}, wantErr: true},
+		{name: "zero length", options: []generatorOption{WithLength(0)}, wantErr: true},
+		{name: "missing reader", options: []generatorOption{WithReader(nil)}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.options...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+	if _, err := New(WithLength(8)); !errors.Is(err, ErrWeakGenerator) {
+		t.Errorf("New() error = %v, want %v", err, ErrWeakGenerator)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	a, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := Generate()
+	if len(a) != DefaultLength || strings.Trim(a, Alphanumeric) != "" || a == b {
+		t.Errorf("Generate() = %v, %v, want distinct alphanumeric values", a, b)
+	}
+}
@@ -0,0 +1,11 @@
+// Package proof implements the key proofing methods of GNAP, which bind a
+// request to the key presented by the client instance. Verification of
+// detached HTTP message signatures ("httpsig"), mutual TLS ("mtls"),
+// detached JWS ("jwsd") and attached JWS ("jws") are supported, both for
+// verifying incoming requests and, through [Signer], for signing outgoing
+// requests.
+//
+// The proof method to use is taken from the Proof member of the presented
+// [models.ClientKey], so that the same key object returned by the AS can be
+// used directly to check requests at the RS.
+package proof // import "github.com/bingxueshuang/gnap/proof"
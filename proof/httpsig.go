@@ -0,0 +1,122 @@
+package proof
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"fmt"
+	"net/http"
+
+	"github.com/bingxueshuang/gnap/models"
+	"github.com/dunglas/httpsfv"
+	"github.com/yaronf/httpsign"
+)
+
+// verifyHTTPSig verifies a request signed with HTTP Message Signatures.
+// The signature must cover the method and target uri, the Authorization
+// header when a token is presented, and the Content-Digest header when the
+// request has a body.
+func verifyHTTPSig(req *http.Request, key models.ClientKey) error {
+	labels, err := signatureLabels(req)
+	if err != nil {
+		return err
+	}
+	k, err := parseKey(key)
+	if err != nil {
+		return err
+	}
+	raw, err := rawKey(k)
+	if err != nil {
+		return err
+	}
+	alg, err := sigAlgOf(key, raw)
+	if err != nil {
+		return err
+	}
+	fields := httpsign.Headers("@method", "@target-uri")
+	if req.Header.Get("Authorization") != "" {
+		fields.AddHeader("authorization")
+	}
+	hasBody := req.Body != nil && req.Body != http.NoBody && req.ContentLength != 0
+	if hasBody {
+		fields.AddHeader("content-digest")
+	}
+	verifier, err := newVerifier(alg, raw, fields)
+	if err != nil {
+		return err
+	}
+	for _, label := range labels {
+		err = httpsign.VerifyRequest(label, *verifier, req)
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidProof, err)
+	}
+	if !hasBody {
+		return nil
+	}
+	err = httpsign.ValidateContentDigestHeader(
+		req.Header.Values("Content-Digest"),
+		&req.Body,
+		[]string{httpsign.DigestSha256, httpsign.DigestSha512},
+	)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidProof, err)
+	}
+	return nil
+}
+
+// signatureLabels lists the signature labels present in the
+// Signature-Input header of the request.
+func signatureLabels(req *http.Request) ([]string, error) {
+	input := req.Header.Values("Signature-Input")
+	if len(input) == 0 {
+		return nil, ErrMissingProof
+	}
+	dict, err := httpsfv.UnmarshalDictionary(input)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidProof, err)
+	}
+	labels := dict.Names()
+	if len(labels) == 0 {
+		return nil, ErrMissingProof
+	}
+	return labels, nil
+}
+
+// newVerifier creates a http signature verifier for the raw key. The
+// keyid parameter is optional in GNAP and hence not verified.
+func newVerifier(alg models.HTTPSigAlg, raw any, fields httpsign.Fields) (*httpsign.Verifier, error) {
+	config := httpsign.NewVerifyConfig().SetVerifyKeyID(false)
+	switch alg {
+	case models.ED25519:
+		if k, ok := raw.(ed25519.PublicKey); ok {
+			return httpsign.NewEd25519Verifier("", k, config, fields)
+		}
+	case models.ECDSA_P256_SHA256:
+		if k, ok := raw.(*ecdsa.PublicKey); ok {
+			return httpsign.NewP256Verifier("", *k, config, fields)
+		}
+	case models.ECDSA_P384_SHA384:
+		if k, ok := raw.(*ecdsa.PublicKey); ok {
+			return httpsign.NewP384Verifier("", *k, config, fields)
+		}
+	case models.RSA_PSS_SHA512:
+		if k, ok := raw.(*rsa.PublicKey); ok {
+			return httpsign.NewRSAPSSVerifier("", *k, config, fields)
+		}
+	case models.RSA_SHA256:
+		if k, ok := raw.(*rsa.PublicKey); ok {
+			return httpsign.NewRSAVerifier("", *k, config, fields)
+		}
+	case models.HMAC_SHA256:
+		if k, ok := raw.([]byte); ok {
+			return httpsign.NewHMACSHA256Verifier("", k, config, fields)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKey, models.ErrInvalidSigAlg)
+	}
+	return nil, fmt.Errorf("%w: key type %T does not match %s", ErrInvalidKey, raw, alg)
+}
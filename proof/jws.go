@@ -0,0 +1,178 @@
+package proof
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bingxueshuang/gnap/models"
+	"github.com/lestrrat-go/jwx/v2/jws"
+)
+
+// Header parameters of the JWS used for key binding.
+const (
+	typJWSD = "gnap-binding-jwsd"
+	typJWS  = "gnap-binding-jws"
+)
+
+// maxSkew is the tolerance when checking the "created" header parameter.
+const maxSkew = 30 * time.Second
+
+// verifyJWSD verifies a detached JWS carried in the Detached-JWS header,
+// computed over the request body.
+func verifyJWSD(req *http.Request, key models.ClientKey) error {
+	sig := req.Header.Get("Detached-JWS")
+	if sig == "" {
+		return ErrMissingProof
+	}
+	body, err := readBody(req)
+	if err != nil {
+		return err
+	}
+	_, err = verifyCompact(req, key, []byte(sig), body, typJWSD)
+	return err
+}
+
+// verifyJWS verifies an attached JWS sent as the request body. On success
+// the request body is replaced by the verified payload. Requests without
+// a body carry the JWS in the Detached-JWS header instead.
+func verifyJWS(req *http.Request, key models.ClientKey) error {
+	body, err := readBody(req)
+	if err != nil {
+		return err
+	}
+	if len(body) == 0 {
+		sig := req.Header.Get("Detached-JWS")
+		if sig == "" {
+			return ErrMissingProof
+		}
+		_, err = verifyCompact(req, key, []byte(sig), nil, typJWS)
+		return err
+	}
+	payload, err := verifyCompact(req, key, body, nil, typJWS)
+	if err != nil {
+		return err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(payload))
+	req.ContentLength = int64(len(payload))
+	return nil
+}
+
+// verifyCompact verifies the compact JWS signature against the key and
+// checks the protected header parameters binding it to the request.
+func verifyCompact(req *http.Request, key models.ClientKey, sig []byte, detached []byte, typ string) ([]byte, error) {
+	k, err := parseKey(key)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := k.PublicKey()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+	raw, err := rawKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	alg, err := jwsAlgOf(k, raw)
+	if err != nil {
+		return nil, err
+	}
+	options := []jws.VerifyOption{jws.WithKey(alg, pub)}
+	if len(detached) > 0 {
+		options = append(options, jws.WithDetachedPayload(detached))
+	}
+	payload, err := jws.Verify(sig, options...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidProof, err)
+	}
+	msg, err := jws.Parse(sig)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidProof, err)
+	}
+	headers := msg.Signatures()[0].ProtectedHeaders()
+	err = checkHeaders(req, headers, typ)
+	if err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// checkHeaders checks the "typ", "htm", "uri", "created" and "ath"
+// header parameters against the request.
+func checkHeaders(req *http.Request, headers jws.Headers, typ string) error {
+	if headers.Type() != typ {
+		return fmt.Errorf("%w: typ mismatch", ErrInvalidProof)
+	}
+	if htm, _ := headers.Get("htm"); htm != req.Method {
+		return fmt.Errorf("%w: htm mismatch", ErrInvalidProof)
+	}
+	if uri, _ := headers.Get("uri"); uri != TargetURI(req) {
+		return fmt.Errorf("%w: uri mismatch", ErrInvalidProof)
+	}
+	created, _ := headers.Get("created")
+	ts, ok := created.(float64)
+	if !ok {
+		return fmt.Errorf("%w: missing created", ErrInvalidProof)
+	}
+	skew := time.Since(time.Unix(int64(ts), 0))
+	if skew > maxSkew || skew < -maxSkew {
+		return fmt.Errorf("%w: created out of range", ErrInvalidProof)
+	}
+	token := AccessToken(req)
+	if token == "" {
+		return nil
+	}
+	if ath, _ := headers.Get("ath"); ath != TokenHash(token) {
+		return fmt.Errorf("%w: ath mismatch", ErrInvalidProof)
+	}
+	return nil
+}
+
+// readBody reads the whole request body and restores it for later use.
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	_ = req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// TargetURI reconstructs the absolute target uri of a server request.
+func TargetURI(req *http.Request) string {
+	if req.URL.IsAbs() {
+		return req.URL.String()
+	}
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + req.Host + req.URL.RequestURI()
+}
+
+// AccessToken extracts the GNAP access token from the Authorization
+// header of the request. It returns empty string if no such token is
+// present.
+func AccessToken(req *http.Request) string {
+	scheme, token, ok := strings.Cut(req.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "GNAP") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// TokenHash computes the "ath" value of the access token, which is the
+// base64url encoded SHA-256 hash of the token value.
+func TokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,130 @@
+package proof
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+
+	"github.com/bingxueshuang/gnap/models"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+)
+
+// parseKey extracts the JWK from the client key.
+func parseKey(key models.ClientKey) (jwk.Key, error) {
+	if len(key.JWK) == 0 {
+		return nil, fmt.Errorf("%w: missing jwk", ErrInvalidKey)
+	}
+	k, err := jwk.ParseKey(key.JWK)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+	return k, nil
+}
+
+// rawKey returns the raw crypto key held by the JWK.
+func rawKey(k jwk.Key) (any, error) {
+	var raw any
+	err := k.Raw(&raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+	return raw, nil
+}
+
+// sigAlgOf determines the http signature algorithm to be used with the key.
+// The algorithm from the proof object takes precedence, then the "alg"
+// parameter of the JWK, and lastly the algorithm implied by the key type.
+func sigAlgOf(key models.ClientKey, raw any) (models.HTTPSigAlg, error) {
+	if sig, ok := key.Proof.(models.HTTPSig); ok && sig.SigAlg != "" {
+		return sig.SigAlg, nil
+	}
+	if alg := jwkAlg(key.JWK); alg != "" {
+		sigAlg, ok := jwsSigAlgs[jwa.SignatureAlgorithm(alg)]
+		if !ok {
+			return "", fmt.Errorf("%w: no http signature algorithm for alg %q", ErrInvalidKey, alg)
+		}
+		return sigAlg, nil
+	}
+	switch k := raw.(type) {
+	case ed25519.PublicKey, ed25519.PrivateKey:
+		return models.ED25519, nil
+	case *ecdsa.PublicKey:
+		return ecdsaSigAlg(k.Curve)
+	case *ecdsa.PrivateKey:
+		return ecdsaSigAlg(k.Curve)
+	case *rsa.PublicKey, *rsa.PrivateKey:
+		return models.RSA_PSS_SHA512, nil
+	case []byte:
+		return models.HMAC_SHA256, nil
+	}
+	return "", fmt.Errorf("%w: unsupported key type %T", ErrInvalidKey, raw)
+}
+
+// jwsSigAlgs maps the JWS algorithms to the http signature algorithms
+// computing the same signature.
+var jwsSigAlgs = map[jwa.SignatureAlgorithm]models.HTTPSigAlg{
+	jwa.EdDSA: models.ED25519,
+	jwa.ES256: models.ECDSA_P256_SHA256,
+	jwa.ES384: models.ECDSA_P384_SHA384,
+	jwa.PS512: models.RSA_PSS_SHA512,
+	jwa.RS256: models.RSA_SHA256,
+	jwa.HS256: models.HMAC_SHA256,
+}
+
+// jwkAlg returns the "alg" parameter of the JWK, if any.
+func jwkAlg(data json.RawMessage) string {
+	var params struct {
+		Alg string `json:"alg"`
+	}
+	_ = json.Unmarshal(data, &params)
+	return params.Alg
+}
+
+// ecdsaSigAlg maps an elliptic curve to its http signature algorithm.
+func ecdsaSigAlg(curve elliptic.Curve) (models.HTTPSigAlg, error) {
+	switch curve {
+	case elliptic.P256():
+		return models.ECDSA_P256_SHA256, nil
+	case elliptic.P384():
+		return models.ECDSA_P384_SHA384, nil
+	}
+	return "", fmt.Errorf("%w: unsupported curve", ErrInvalidKey)
+}
+
+// jwsAlgOf determines the JWS algorithm to be used with the key, from the
+// "alg" parameter of the JWK or else from the key type.
+func jwsAlgOf(k jwk.Key, raw any) (jwa.SignatureAlgorithm, error) {
+	if alg := k.Algorithm(); alg != nil && alg.String() != "" {
+		return jwa.SignatureAlgorithm(alg.String()), nil
+	}
+	switch k := raw.(type) {
+	case ed25519.PublicKey, ed25519.PrivateKey:
+		return jwa.EdDSA, nil
+	case *ecdsa.PublicKey:
+		return ecdsaJWSAlg(k.Curve)
+	case *ecdsa.PrivateKey:
+		return ecdsaJWSAlg(k.Curve)
+	case *rsa.PublicKey, *rsa.PrivateKey:
+		return jwa.PS512, nil
+	case []byte:
+		return jwa.HS256, nil
+	}
+	return "", fmt.Errorf("%w: unsupported key type %T", ErrInvalidKey, raw)
+}
+
+// ecdsaJWSAlg maps an elliptic curve to its JWS algorithm.
+func ecdsaJWSAlg(curve elliptic.Curve) (jwa.SignatureAlgorithm, error) {
+	switch curve {
+	case elliptic.P256():
+		return jwa.ES256, nil
+	case elliptic.P384():
+		return jwa.ES384, nil
+	case elliptic.P521():
+		return jwa.ES512, nil
+	}
+	return "", fmt.Errorf("%w: unsupported curve", ErrInvalidKey)
+}
@@ -0,0 +1,73 @@
+package proof
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+
+	"github.com/bingxueshuang/gnap/models"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+)
+
+// verifyMTLS verifies that the client certificate presented during the
+// TLS handshake matches the key. The key may carry the certificate itself,
+// its SHA-256 thumbprint or the public key as a JWK.
+func verifyMTLS(req *http.Request, key models.ClientKey) error {
+	if req.TLS == nil || len(req.TLS.PeerCertificates) == 0 {
+		return ErrMissingProof
+	}
+	cert := req.TLS.PeerCertificates[0]
+	switch {
+	case key.Cert != "":
+		der, err := base64.StdEncoding.DecodeString(key.Cert)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidKey, err)
+		}
+		if !bytes.Equal(der, cert.Raw) {
+			return ErrInvalidProof
+		}
+		return nil
+	case key.CertS256 != "":
+		sum := sha256.Sum256(cert.Raw)
+		if key.CertS256 != base64.RawURLEncoding.EncodeToString(sum[:]) {
+			return ErrInvalidProof
+		}
+		return nil
+	case len(key.JWK) != 0:
+		return matchJWK(key, cert)
+	}
+	return fmt.Errorf("%w: missing key material", ErrInvalidKey)
+}
+
+// matchJWK compares the public key of the certificate with the JWK
+// of the client key by their thumbprints.
+func matchJWK(key models.ClientKey, cert *x509.Certificate) error {
+	k, err := parseKey(key)
+	if err != nil {
+		return err
+	}
+	pub, err := k.PublicKey()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+	certKey, err := jwk.FromRaw(cert.PublicKey)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidProof, err)
+	}
+	want, err := pub.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+	got, err := certKey.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidProof, err)
+	}
+	if !bytes.Equal(want, got) {
+		return ErrInvalidProof
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package proof
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/bingxueshuang/gnap/models"
+)
+
+// Errors during verification of key proofs.
+var (
+	ErrUnsupportedMethod = errors.New("unsupported proof method")
+	ErrInvalidKey        = errors.New("invalid key")
+	ErrMissingProof      = errors.New("missing key proof")
+	ErrInvalidProof      = errors.New("invalid key proof")
+)
+
+// Verify checks that req carries a valid proof of possession of key,
+// using the proof method declared by the key. Keys passed by reference
+// cannot be verified and must be resolved by the caller beforehand.
+func Verify(req *http.Request, key models.ClientKey) error {
+	if key.Ref != "" || key.Proof == nil {
+		return ErrInvalidKey
+	}
+	switch key.Proof.Proof() {
+	case models.ProofHTTPSig:
+		return verifyHTTPSig(req, key)
+	case models.ProofMTLS:
+		return verifyMTLS(req, key)
+	case models.ProofJWSD:
+		return verifyJWSD(req, key)
+	case models.ProofJWS:
+		return verifyJWS(req, key)
+	}
+	return ErrUnsupportedMethod
+}
+
+// Method reports the proof method that req appears to use, judging by
+// its headers and TLS connection only. The proof is not verified. It
+// returns empty string for requests without key proof, as for bearer
+// tokens.
+func Method(req *http.Request) models.ProofMethod {
+	switch {
+	case req.Header.Get("Signature-Input") != "":
+		return models.ProofHTTPSig
+	case req.Header.Get("Detached-JWS") != "":
+		// bodyless "jws" requests also use the Detached-JWS header
+		if jwsType(req.Header.Get("Detached-JWS")) == typJWS {
+			return models.ProofJWS
+		}
+		return models.ProofJWSD
+	case strings.HasPrefix(req.Header.Get("Content-Type"), "application/jose"):
+		return models.ProofJWS
+	case req.TLS != nil && len(req.TLS.PeerCertificates) > 0:
+		return models.ProofMTLS
+	}
+	return ""
+}
+
+// jwsType returns the "typ" header parameter of a compact JWS, without
+// verifying it.
+func jwsType(compact string) string {
+	protected, _, _ := strings.Cut(compact, ".")
+	data, err := base64.RawURLEncoding.DecodeString(protected)
+	if err != nil {
+		return ""
+	}
+	var headers struct {
+		Type string `json:"typ"`
+	}
+	_ = json.Unmarshal(data, &headers)
+	return headers.Type
+}
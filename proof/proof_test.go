@@ -0,0 +1,228 @@
+package proof
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bingxueshuang/gnap/models"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+)
+
+// testSigner creates a signer for a fresh ed25519 key with the given
+// proof method.
+func testSigner(t *testing.T, method models.ProofMethod) *Signer {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := jwk.FromRaw(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := k.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := json.Marshal(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := NewSigner(models.ClientKey{Proof: method, JWK: raw}, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+func TestVerify(t *testing.T) {
+	signers := map[models.ProofMethod]*Signer{
+		models.ProofHTTPSig: testSigner(t, models.ProofHTTPSig),
+		models.ProofJWSD:    testSigner(t, models.ProofJWSD),
+		models.ProofJWS:     testSigner(t, models.ProofJWS),
+	}
+	setBody := func(body string) func(*http.Request) {
+		return func(req *http.Request) {
+			req.Body = http.NoBody
+			if body != "" {
+				req.Body = io.NopCloser(strings.NewReader(body))
+			}
+			req.ContentLength = int64(len(body))
+		}
+	}
+	setHeader := func(name, value string) func(*http.Request) {
+		return func(req *http.Request) { req.Header.Set(name, value) }
+	}
+	setMethod := func(method string) func(*http.Request) {
+		return func(req *http.Request) { req.Method = method }
+	}
+	setPath := func(path string) func(*http.Request) {
+		return func(req *http.Request) { req.URL.Path = path }
+	}
+	otherDigest := "sha-256=:" + base64.StdEncoding.EncodeToString(make([]byte, sha256.Size)) + ":"
+	tests := []struct {
+		name    string
+		method  models.ProofMethod
+		body    string
+		created time.Duration // age of the proof when signed
+		tamper  func(*http.Request)
+		wantErr error
+	}{
+		{name: "httpsig", method: models.ProofHTTPSig, body: `{"photo":"a"}`},
+		{name: "httpsig without body", method: models.ProofHTTPSig},
+		{name: "httpsig tampered body", method: models.ProofHTTPSig, body: `{"photo":"a"}`, tamper: setBody(`{"photo":"b"}`), wantErr: ErrInvalidProof},
+		{name: "httpsig tampered digest", method: models.ProofHTTPSig, body: `{"photo":"a"}`, tamper: setHeader("Content-Digest", otherDigest), wantErr: ErrInvalidProof},
+		{name: "httpsig wrong method", method: models.ProofHTTPSig, tamper: setMethod(http.MethodPut), wantErr: ErrInvalidProof},
+		{name: "httpsig wrong uri", method: models.ProofHTTPSig, tamper: setPath("/admin"), wantErr: ErrInvalidProof},
+		{name: "httpsig wrong token", method: models.ProofHTTPSig, tamper: setHeader("Authorization", "GNAP other"), wantErr: ErrInvalidProof},
+		{name: "httpsig unsigned", method: models.ProofHTTPSig, tamper: setHeader("Signature-Input", ""), wantErr: ErrMissingProof},
+		{name: "jwsd", method: models.ProofJWSD, body: `{"photo":"a"}`},
+		{name: "jwsd tampered body", method: models.ProofJWSD, body: `{"photo":"a"}`, tamper: setBody(`{"photo":"b"}`), wantErr: ErrInvalidProof},
+		{name: "jwsd wrong htm", method: models.ProofJWSD, body: `{"photo":"a"}`, tamper: setMethod(http.MethodPut), wantErr: ErrInvalidProof},
+		{name: "jwsd wrong uri", method: models.ProofJWSD, body: `{"photo":"a"}`, tamper: setPath("/admin"), wantErr: ErrInvalidProof},
+		{name: "jwsd stale created", method: models.ProofJWSD, body: `{"photo":"a"}`, created: time.Minute, wantErr: ErrInvalidProof},
+		{name: "jwsd wrong ath", method: models.ProofJWSD, body: `{"photo":"a"}`, tamper: setHeader("Authorization", "GNAP other"), wantErr: ErrInvalidProof},
+		{name: "jwsd missing", method: models.ProofJWSD, body: `{"photo":"a"}`, tamper: setHeader("Detached-JWS", ""), wantErr: ErrMissingProof},
+		{name: "jws", method: models.ProofJWS, body: `{"photo":"a"}`},
+		{name: "jws without body", method: models.ProofJWS},
+		{name: "jws wrong htm", method: models.ProofJWS, body: `{"photo":"a"}`, tamper: setMethod(http.MethodPut), wantErr: ErrInvalidProof},
+		{name: "jws wrong uri", method: models.ProofJWS, tamper: setPath("/admin"), wantErr: ErrInvalidProof},
+		{name: "jws stale created", method: models.ProofJWS, body: `{"photo":"a"}`, created: time.Minute, wantErr: ErrInvalidProof},
+		{name: "jws created in future", method: models.ProofJWS, created: -time.Minute, wantErr: ErrInvalidProof},
+		{name: "jws wrong ath", method: models.ProofJWS, tamper: setHeader("Authorization", "GNAP other"), wantErr: ErrInvalidProof},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer := *signers[tt.method]
+			signer.now = func() time.Time { return time.Now().Add(-tt.created) }
+			method := http.MethodGet
+			if tt.body != "" {
+				method = http.MethodPost
+			}
+			req, err := http.NewRequest(method, "https://rs.example.com/photos", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.body == "" {
+				req.Body, req.ContentLength = http.NoBody, 0
+			}
+			req.Header.Set("Authorization", "GNAP OS9M2PMHKUR64TB8N6BW7OZB8CDFONP219RP1LT0")
+			if err = signer.Sign(req); err != nil {
+				t.Fatal(err)
+			}
+			if tt.tamper != nil {
+				tt.tamper(req)
+			}
+			err = Verify(req, signer.Key())
+			if !errors.Is(err, tt.wantErr) || (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// testCert creates a self-signed certificate for a fresh ed25519 key.
+func testCert(t *testing.T) (*x509.Certificate, ed25519.PublicKey) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "client.example.net"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, pub, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, pub
+}
+
+func TestVerify_mtls(t *testing.T) {
+	cert, pub := testCert(t)
+	other, otherPub := testCert(t)
+	thumbprint := func(cert *x509.Certificate) string {
+		sum := sha256.Sum256(cert.Raw)
+		return base64.RawURLEncoding.EncodeToString(sum[:])
+	}
+	jwkOf := func(pub ed25519.PublicKey) json.RawMessage {
+		k, err := jwk.FromRaw(pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw, err := json.Marshal(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return raw
+	}
+	tests := []struct {
+		name    string
+		key     models.ClientKey
+		peer    *x509.Certificate
+		wantErr error
+	}{
+		{name: "certificate", key: models.ClientKey{Proof: models.ProofMTLS, Cert: base64.StdEncoding.EncodeToString(cert.Raw)}, peer: cert},
+		{name: "thumbprint", key: models.ClientKey{Proof: models.ProofMTLS, CertS256: thumbprint(cert)}, peer: cert},
+		{name: "jwk", key: models.ClientKey{Proof: models.ProofMTLS, JWK: jwkOf(pub)}, peer: cert},
+		{name: "other certificate", key: models.ClientKey{Proof: models.ProofMTLS, Cert: base64.StdEncoding.EncodeToString(other.Raw)}, peer: cert, wantErr: ErrInvalidProof},
+		{name: "thumbprint mismatch", key: models.ClientKey{Proof: models.ProofMTLS, CertS256: thumbprint(other)}, peer: cert, wantErr: ErrInvalidProof},
+		{name: "jwk mismatch", key: models.ClientKey{Proof: models.ProofMTLS, JWK: jwkOf(otherPub)}, peer: cert, wantErr: ErrInvalidProof},
+		{name: "no client certificate", key: models.ClientKey{Proof: models.ProofMTLS, CertS256: thumbprint(cert)}, wantErr: ErrMissingProof},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "https://rs.example.com/photos", nil)
+			if tt.peer != nil {
+				req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{tt.peer}}
+			}
+			err := Verify(req, tt.key)
+			if !errors.Is(err, tt.wantErr) || (err != nil) != (tt.wantErr != nil) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSigAlgOf(t *testing.T) {
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	tests := []struct {
+		name    string
+		key     models.ClientKey
+		want    models.HTTPSigAlg
+		wantErr bool
+	}{
+		{name: "key type", key: models.ClientKey{Proof: models.ProofHTTPSig, JWK: json.RawMessage(`{"kty":"OKP"}`)}, want: models.ED25519},
+		{name: "jwk alg", key: models.ClientKey{Proof: models.ProofHTTPSig, JWK: json.RawMessage(`{"kty":"OKP","alg":"RS256"}`)}, want: models.RSA_SHA256},
+		{name: "proof alg", key: models.ClientKey{Proof: models.HTTPSig{Method: models.ProofHTTPSig, SigAlg: models.RSA_PSS_SHA512}, JWK: json.RawMessage(`{"kty":"OKP","alg":"RS256"}`)}, want: models.RSA_PSS_SHA512},
+		{name: "unmapped jwk alg", key: models.ClientKey{Proof: models.ProofHTTPSig, JWK: json.RawMessage(`{"kty":"OKP","alg":"RS512"}`)}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sigAlgOf(tt.key, priv)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("sigAlgOf() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
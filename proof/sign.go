@@ -0,0 +1,287 @@
+package proof
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/bingxueshuang/gnap/models"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/yaronf/httpsign"
+)
+
+// Signer attaches proofs of possession of a key to outgoing requests,
+// using the proof method declared by the key. Requests signed by it are
+// accepted by [Verify] with the same key.
+type Signer struct {
+	key  models.ClientKey
+	priv jwk.Key
+	now  func() time.Time
+}
+
+// NewSigner is the constructor for [Signer] with the public key as
+// presented to the AS and the matching private key. The private key may
+// be nil for the "mtls" proof method, where the proof is given by the
+// client certificate configured on the TLS connection instead.
+func NewSigner(key models.ClientKey, priv jwk.Key) (*Signer, error) {
+	if key.Ref != "" || key.Proof == nil {
+		return nil, ErrInvalidKey
+	}
+	s := &Signer{key: key, priv: priv, now: time.Now}
+	if key.Proof.Proof() == models.ProofMTLS {
+		return s, nil
+	}
+	if priv == nil {
+		return nil, fmt.Errorf("%w: missing private key", ErrInvalidKey)
+	}
+	if len(key.JWK) == 0 {
+		return s, nil
+	}
+	err := matchPrivate(key, priv)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Key returns the public key of the signer.
+func (s *Signer) Key() models.ClientKey {
+	return s.key
+}
+
+// Sign adds the key proof to req. Any Authorization header must be set
+// beforehand, as it is covered by the proof. The request body is read and
+// replaced; for the "jws" method it is replaced by the signed payload.
+func (s *Signer) Sign(req *http.Request) error {
+	switch s.key.Proof.Proof() {
+	case models.ProofHTTPSig:
+		return s.signHTTPSig(req)
+	case models.ProofMTLS:
+		return nil
+	case models.ProofJWSD:
+		return s.signJWSD(req)
+	case models.ProofJWS:
+		return s.signJWS(req)
+	}
+	return ErrUnsupportedMethod
+}
+
+// signHTTPSig signs the request with HTTP Message Signatures, covering
+// the same fields that verifyHTTPSig requires.
+func (s *Signer) signHTTPSig(req *http.Request) error {
+	raw, err := rawKey(s.priv)
+	if err != nil {
+		return err
+	}
+	alg, err := sigAlgOf(s.key, raw)
+	if err != nil {
+		return err
+	}
+	fields := httpsign.Headers("@method", "@target-uri")
+	if req.Header.Get("Authorization") != "" {
+		fields.AddHeader("authorization")
+	}
+	body, err := readRequestBody(req)
+	if err != nil {
+		return err
+	}
+	if len(body) > 0 {
+		digest := models.DigestSha256
+		if sig, ok := s.key.Proof.(models.HTTPSig); ok && sig.DigestAlg != "" {
+			digest = sig.DigestAlg
+		}
+		header, err := httpsign.GenerateContentDigestHeader(&req.Body, []string{string(digest)})
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Digest", header)
+		fields.AddHeader("content-digest")
+	}
+	kid, err := keyID(s.priv)
+	if err != nil {
+		return err
+	}
+	signer, err := newSigner(alg, kid, raw, fields)
+	if err != nil {
+		return err
+	}
+	input, sig, err := httpsign.SignRequest("sig1", *signer, req)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Signature-Input", input)
+	req.Header.Set("Signature", sig)
+	return nil
+}
+
+// signJWSD signs the request body with a detached JWS, carried in the
+// Detached-JWS header.
+func (s *Signer) signJWSD(req *http.Request) error {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return err
+	}
+	sig, err := s.signCompact(req, body, typJWSD, len(body) > 0)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Detached-JWS", string(sig))
+	return nil
+}
+
+// signJWS replaces the request body with an attached JWS over it.
+// Requests without a body carry the JWS in the Detached-JWS header.
+func (s *Signer) signJWS(req *http.Request) error {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return err
+	}
+	sig, err := s.signCompact(req, body, typJWS, false)
+	if err != nil {
+		return err
+	}
+	if len(body) == 0 {
+		req.Header.Set("Detached-JWS", string(sig))
+		return nil
+	}
+	setBody(req, sig)
+	req.Header.Set("Content-Type", "application/jose")
+	return nil
+}
+
+// signCompact creates the compact JWS of the payload, with the protected
+// header parameters that checkHeaders expects.
+func (s *Signer) signCompact(req *http.Request, payload []byte, typ string, detached bool) ([]byte, error) {
+	raw, err := rawKey(s.priv)
+	if err != nil {
+		return nil, err
+	}
+	alg, err := jwsAlgOf(s.priv, raw)
+	if err != nil {
+		return nil, err
+	}
+	headers := jws.NewHeaders()
+	_ = headers.Set(jws.TypeKey, typ)
+	_ = headers.Set("htm", req.Method)
+	_ = headers.Set("uri", req.URL.String())
+	_ = headers.Set("created", s.now().Unix())
+	if kid := s.priv.KeyID(); kid != "" {
+		_ = headers.Set(jws.KeyIDKey, kid)
+	}
+	if token := AccessToken(req); token != "" {
+		_ = headers.Set("ath", TokenHash(token))
+	}
+	options := []jws.SignOption{jws.WithKey(alg, s.priv, jws.WithProtectedHeaders(headers))}
+	if detached {
+		options = append(options, jws.WithDetachedPayload(payload))
+		payload = nil
+	}
+	sig, err := jws.Sign(payload, options...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+	return sig, nil
+}
+
+// readRequestBody reads the whole body of an outgoing request and
+// restores it, so that it can be read again and replayed on redirects.
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	_ = req.Body.Close()
+	setBody(req, body)
+	return body, nil
+}
+
+// setBody replaces the body of an outgoing request.
+func setBody(req *http.Request, body []byte) {
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+}
+
+// matchPrivate checks that the private key belongs to the public key
+// by comparing their thumbprints.
+func matchPrivate(key models.ClientKey, priv jwk.Key) error {
+	k, err := parseKey(key)
+	if err != nil {
+		return err
+	}
+	pub, err := priv.PublicKey()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+	want, err := k.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+	got, err := pub.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+	if !bytes.Equal(want, got) {
+		return fmt.Errorf("%w: private key does not match", ErrInvalidKey)
+	}
+	return nil
+}
+
+// keyID returns the "kid" parameter of the JWK, or else its SHA-256
+// thumbprint, since http signatures require a key identifier.
+func keyID(k jwk.Key) (string, error) {
+	if kid := k.KeyID(); kid != "" {
+		return kid, nil
+	}
+	sum, err := k.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+	return base64.RawURLEncoding.EncodeToString(sum), nil
+}
+
+// newSigner creates a http signature signer for the raw private key.
+func newSigner(alg models.HTTPSigAlg, keyID string, raw any, fields httpsign.Fields) (*httpsign.Signer, error) {
+	switch alg {
+	case models.ED25519:
+		if k, ok := raw.(ed25519.PrivateKey); ok {
+			return httpsign.NewEd25519Signer(keyID, k, nil, fields)
+		}
+	case models.ECDSA_P256_SHA256:
+		if k, ok := raw.(*ecdsa.PrivateKey); ok {
+			return httpsign.NewP256Signer(keyID, *k, nil, fields)
+		}
+	case models.ECDSA_P384_SHA384:
+		if k, ok := raw.(*ecdsa.PrivateKey); ok {
+			return httpsign.NewP384Signer(keyID, *k, nil, fields)
+		}
+	case models.RSA_PSS_SHA512:
+		if k, ok := raw.(*rsa.PrivateKey); ok {
+			return httpsign.NewRSAPSSSigner(keyID, *k, nil, fields)
+		}
+	case models.RSA_SHA256:
+		if k, ok := raw.(*rsa.PrivateKey); ok {
+			return httpsign.NewRSASigner(keyID, *k, nil, fields)
+		}
+	case models.HMAC_SHA256:
+		if k, ok := raw.([]byte); ok {
+			return httpsign.NewHMACSHA256Signer(keyID, k, nil, fields)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKey, models.ErrInvalidSigAlg)
+	}
+	return nil, fmt.Errorf("%w: key type %T does not match %s", ErrInvalidKey, raw, alg)
+}
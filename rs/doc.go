@@ -0,0 +1,11 @@
+// Package rs provides the resource server side of GNAP: a [net/http]
+// middleware that accepts access tokens presented with the "GNAP"
+// authorization scheme, validates them and verifies the key proof bound
+// to the token.
+//
+// Tokens are validated by a [Validator]. [JWTValidator] handles
+// self-contained tokens issued as signed JWTs, while [Introspector]
+// asks the introspection endpoint of the AS about opaque tokens. On success, the granted
+// access rights are available to the wrapped handler through
+// [AccessFromContext].
+package rs // import "github.com/bingxueshuang/gnap/rs"
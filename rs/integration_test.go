@@ -0,0 +1,143 @@
+package rs
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bingxueshuang/gnap/as"
+	"github.com/bingxueshuang/gnap/models"
+	"github.com/bingxueshuang/gnap/proof"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+)
+
+// testSigner creates a signer for a fresh ed25519 key with the given
+// proof method.
+func testSigner(t *testing.T, method models.ProofMethod) *proof.Signer {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := jwk.FromRaw(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := k.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := json.Marshal(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var proofer models.Proofer = method
+	if method == models.ProofHTTPSig {
+		proofer = models.HTTPSig{Method: method, SigAlg: models.ED25519}
+	}
+	signer, err := proof.NewSigner(models.ClientKey{Proof: proofer, JWK: raw}, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+// TestIntrospection checks the RS validating tokens by introspection at
+// the AS, through the handlers of both packages.
+func TestIntrospection(t *testing.T) {
+	access := []models.AccessRight{{Type: "photo-api", Actions: []string{"read"}}}
+	signers := map[models.ProofMethod]*proof.Signer{
+		models.ProofHTTPSig: testSigner(t, models.ProofHTTPSig),
+		models.ProofJWSD:    testSigner(t, models.ProofJWSD),
+		models.ProofJWS:     testSigner(t, models.ProofJWS),
+	}
+	store := &as.MemoryTokenStore{}
+	ctx := context.Background()
+	for method, signer := range signers {
+		key := signer.Key()
+		err := store.Put(ctx, as.Token{Value: string(method), Access: access, Key: &key, ExpiresAt: time.Now().Add(time.Hour)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := store.Put(ctx, as.Token{Value: "bearer", Access: access, Flags: []models.TokenFlag{models.FlagBearer}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler, err := as.NewIntrospectionHandler(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	asrv := httptest.NewServer(handler)
+	defer asrv.Close()
+
+	in, err := NewIntrospector(asrv.URL, models.RSIdentity{Ref: "7C7C4AZ9KHRS6X63AJAO"}, WithCacheTTL(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewMiddleware(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsrv := httptest.NewServer(m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !Allowed(r.Context(), access...) {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	})))
+	defer rsrv.Close()
+
+	tests := []struct {
+		name   string
+		token  string
+		signer *proof.Signer
+		body   string
+		status int
+	}{
+		{name: "httpsig", token: "httpsig", signer: signers[models.ProofHTTPSig], status: http.StatusOK},
+		{name: "jwsd", token: "jwsd", signer: signers[models.ProofJWSD], body: `{"photo":"a"}`, status: http.StatusOK},
+		{name: "jws", token: "jws", signer: signers[models.ProofJWS], body: `{"photo":"a"}`, status: http.StatusOK},
+		{name: "jws without body", token: "jws", signer: signers[models.ProofJWS], status: http.StatusOK},
+		{name: "bearer", token: "bearer", status: http.StatusOK},
+		{name: "bound without proof", token: "httpsig", status: http.StatusUnauthorized},
+		{name: "other proof method", token: "httpsig", signer: signers[models.ProofJWSD], body: `{"photo":"a"}`, status: http.StatusUnauthorized},
+		{name: "unknown", token: "unknown", signer: signers[models.ProofHTTPSig], status: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := http.MethodGet
+			var body io.Reader
+			if tt.body != "" {
+				method = http.MethodPost
+				body = strings.NewReader(tt.body)
+			}
+			req, err := http.NewRequest(method, rsrv.URL+"/photos", body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Authorization", "GNAP "+tt.token)
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			if tt.signer != nil {
+				if err = tt.signer.Sign(req); err != nil {
+					t.Fatal(err)
+				}
+			}
+			resp, err := rsrv.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("Middleware.Handler() status = %v, want %v", resp.StatusCode, tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package rs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bingxueshuang/gnap/models"
+)
+
+// Introspector is a [Validator] that asks the introspection endpoint of
+// the AS about opaque access tokens. Results are cached for a short
+// time, never beyond the expiry of the token.
+type Introspector struct {
+	endpoint string
+	rs       models.RSIdentity
+	client   *http.Client
+	ttl      time.Duration
+	now      func() time.Time
+
+	mu    sync.Mutex
+	cache map[cacheKey]cached
+}
+
+// cacheKey identifies an introspection result. The result depends on
+// the proof method the token was presented with.
+type cacheKey struct {
+	token  string
+	method models.ProofMethod
+}
+
+// cached is an introspection result along with its cache expiry.
+type cached struct {
+	info   TokenInfo
+	err    error
+	expiry time.Time
+}
+
+// NewIntrospector is the constructor for [Introspector] with mandatory
+// introspection endpoint and identity of the RS, and optional parameters.
+func NewIntrospector(endpoint string, rs models.RSIdentity, options ...introspectorOption) (in *Introspector, err error) {
+	in = &Introspector{
+		endpoint: endpoint,
+		rs:       rs,
+		client:   http.DefaultClient,
+		ttl:      30 * time.Second,
+		now:      time.Now,
+		cache:    make(map[cacheKey]cached),
+	}
+	for _, setter := range options {
+		err = setter(in)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return in, nil
+}
+
+// introspectorOption is functional parameter for introspector constructor.
+type introspectorOption func(*Introspector) error
+
+// WithHTTPClient is an optional parameter for [NewIntrospector] to set
+// the client used to call the AS, typically one that signs the request
+// with the key of the RS.
+func WithHTTPClient(client *http.Client) introspectorOption {
+	return func(in *Introspector) error {
+		in.client = client
+		return nil
+	}
+}
+
+// WithCacheTTL is an optional parameter for [NewIntrospector] to set
+// how long introspection results are reused. Zero disables caching.
+func WithCacheTTL(ttl time.Duration) introspectorOption {
+	return func(in *Introspector) error {
+		if ttl < 0 {
+			return fmt.Errorf("negative cache ttl: %v", ttl)
+		}
+		in.ttl = ttl
+		return nil
+	}
+}
+
+// Validate implements [Validator] interface. The proof method is sent
+// to the AS, which reports key-bound tokens presented without the proof
+// method of their key as not active.
+func (in *Introspector) Validate(ctx context.Context, token string, method models.ProofMethod) (TokenInfo, error) {
+	now := in.now()
+	key := cacheKey{token: token, method: method}
+	in.mu.Lock()
+	entry, ok := in.cache[key]
+	in.mu.Unlock()
+	if ok && now.Before(entry.expiry) {
+		return entry.info, entry.err
+	}
+	res, err := in.Introspect(ctx, token, method)
+	if err != nil {
+		return TokenInfo{}, err // transport errors are not cached
+	}
+	entry = cached{expiry: now.Add(in.ttl)}
+	if res.Active {
+		entry.info = TokenInfo{
+			Access:     res.Access,
+			Key:        res.Key,
+			Flags:      res.Flags,
+			InstanceID: res.InstanceID,
+		}
+		if res.ExpiresAt != 0 {
+			entry.info.ExpiresAt = time.Unix(res.ExpiresAt, 0)
+			if entry.info.ExpiresAt.Before(entry.expiry) {
+				entry.expiry = entry.info.ExpiresAt
+			}
+		}
+	} else {
+		entry.err = fmt.Errorf("%w: token not active", ErrInvalidToken)
+	}
+	if in.ttl > 0 {
+		in.mu.Lock()
+		in.purge(now)
+		in.cache[key] = entry
+		in.mu.Unlock()
+	}
+	return entry.info, entry.err
+}
+
+// purge drops stale cache entries. Caller must hold the lock.
+func (in *Introspector) purge(now time.Time) {
+	for key, entry := range in.cache {
+		if !now.Before(entry.expiry) {
+			delete(in.cache, key)
+		}
+	}
+}
+
+// Introspect calls the introspection endpoint of the AS for the given
+// token presented with the proof method, bypassing the cache.
+func (in *Introspector) Introspect(ctx context.Context, token string, method models.ProofMethod) (models.IntrospectResponse, error) {
+	body, err := json.Marshal(models.IntrospectRequest{
+		AccessToken:    token,
+		Proof:          method,
+		ResourceServer: in.rs,
+	})
+	if err != nil {
+		return models.IntrospectResponse{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, in.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return models.IntrospectResponse{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	resp, err := in.client.Do(req)
+	if err != nil {
+		return models.IntrospectResponse{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return models.IntrospectResponse{}, fmt.Errorf("introspection: %w", models.ReadError(resp))
+	}
+	var res models.IntrospectResponse
+	err = json.NewDecoder(resp.Body).Decode(&res)
+	if err != nil {
+		return models.IntrospectResponse{}, fmt.Errorf("introspection: %w", err)
+	}
+	return res, nil
+}
@@ -0,0 +1,60 @@
+package rs
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bingxueshuang/gnap/models"
+)
+
+func TestIntrospector_Validate(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		var req models.IntrospectRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req.ResourceServer.Ref != "7C7C4AZ9KHRS6X63AJAO" {
+			t.Errorf("resource_server = %v", req.ResourceServer)
+		}
+		res := models.IntrospectResponse{Active: req.AccessToken == "active"}
+		if res.Active {
+			res.Access = []models.AccessRight{{Ref: "dolphin-metadata"}}
+			res.Flags = []models.TokenFlag{models.FlagBearer}
+		}
+		_ = json.NewEncoder(w).Encode(res)
+	}))
+	defer srv.Close()
+	now := time.Now()
+	in, err := NewIntrospector(srv.URL, models.RSIdentity{Ref: "7C7C4AZ9KHRS6X63AJAO"}, WithCacheTTL(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	in.now = func() time.Time { return now }
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		info, err := in.Validate(ctx, "active", "")
+		if err != nil {
+			t.Fatalf("Introspector.Validate() error = %v", err)
+		}
+		if !info.IsBearer() || len(info.Access) != 1 || info.Access[0].Ref != "dolphin-metadata" {
+			t.Errorf("Introspector.Validate() = %+v", info)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("introspection calls = %v, want 1 (cached)", got)
+	}
+	now = now.Add(2 * time.Minute)
+	_, _ = in.Validate(ctx, "active", "")
+	if got := calls.Load(); got != 2 {
+		t.Errorf("introspection calls = %v, want 2 (cache expired)", got)
+	}
+	_, err = in.Validate(ctx, "inactive", "")
+	if err == nil {
+		t.Errorf("Introspector.Validate() inactive token error = nil, want error")
+	}
+}
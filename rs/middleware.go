@@ -0,0 +1,144 @@
+package rs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/bingxueshuang/gnap/models"
+	"github.com/bingxueshuang/gnap/proof"
+)
+
+// ErrMissingToken is returned when the request does not carry an
+// access token with the "GNAP" authorization scheme.
+var ErrMissingToken = errors.New("missing access token")
+
+// ErrUnboundToken is returned when a token not flagged as bearer
+// is not bound to any key.
+var ErrUnboundToken = errors.New("access token not bound to a key")
+
+// contextKey is the type of context keys defined by this package.
+type contextKey struct{}
+
+// tokenKey is the context key for the validated [TokenInfo].
+var tokenKey contextKey
+
+// Middleware protects http handlers with GNAP access tokens.
+type Middleware struct {
+	validator Validator
+	asURI     string
+	onError   func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// NewMiddleware is the constructor for [Middleware] with mandatory
+// token validator and optional parameters.
+func NewMiddleware(validator Validator, options ...middlewareOption) (m *Middleware, err error) {
+	m = &Middleware{validator: validator}
+	for _, setter := range options {
+		err = setter(m)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if m.onError == nil {
+		m.onError = m.unauthorized
+	}
+	return m, nil
+}
+
+// middlewareOption is functional parameter for middleware constructor.
+type middlewareOption func(*Middleware) error
+
+// WithASURI is an optional parameter for [NewMiddleware] to advertise
+// the grant endpoint of the AS in the WWW-Authenticate header of
+// rejected requests.
+func WithASURI(uri string) middlewareOption {
+	return func(m *Middleware) error {
+		m.asURI = uri
+		return nil
+	}
+}
+
+// WithErrorHandler is an optional parameter for [NewMiddleware] to
+// customize the response when the request is not authorized.
+func WithErrorHandler(handler func(w http.ResponseWriter, r *http.Request, err error)) middlewareOption {
+	return func(m *Middleware) error {
+		m.onError = handler
+		return nil
+	}
+}
+
+// Handler wraps next so that it is only called for requests carrying a
+// valid access token, with a valid key proof unless the token is a
+// bearer token.
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info, err := m.authorize(r)
+		if err != nil {
+			m.onError(w, r, err)
+			return
+		}
+		ctx := context.WithValue(r.Context(), tokenKey, info)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// authorize validates the token presented with the request and
+// verifies the key proof.
+func (m *Middleware) authorize(r *http.Request) (TokenInfo, error) {
+	token := proof.AccessToken(r)
+	if token == "" {
+		return TokenInfo{}, ErrMissingToken
+	}
+	info, err := m.validator.Validate(r.Context(), token, proof.Method(r))
+	if err != nil {
+		return TokenInfo{}, err
+	}
+	if info.IsBearer() {
+		if info.Key != nil { // conflicting flags, see models.TokenResponse.Validate
+			return TokenInfo{}, fmt.Errorf("%w: bearer token bound to a key", ErrInvalidToken)
+		}
+		return info, nil
+	}
+	if info.Key == nil {
+		return TokenInfo{}, ErrUnboundToken
+	}
+	err = proof.Verify(r, *info.Key)
+	if err != nil {
+		return TokenInfo{}, err
+	}
+	return info, nil
+}
+
+// unauthorized is the default error handler, responding with status
+// 401 and a WWW-Authenticate challenge for the "GNAP" scheme.
+func (m *Middleware) unauthorized(w http.ResponseWriter, r *http.Request, err error) {
+	challenge := "GNAP"
+	if m.asURI != "" {
+		challenge = fmt.Sprintf("GNAP as_uri=%q", m.asURI)
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+}
+
+// TokenFromContext returns the validated token stored in ctx by the
+// [Middleware], if any.
+func TokenFromContext(ctx context.Context) (TokenInfo, bool) {
+	info, ok := ctx.Value(tokenKey).(TokenInfo)
+	return info, ok
+}
+
+// AccessFromContext returns the access rights granted to the token
+// stored in ctx by the [Middleware].
+func AccessFromContext(ctx context.Context) []models.AccessRight {
+	info, _ := TokenFromContext(ctx)
+	return info.Access
+}
+
+// Allowed reports whether the token stored in ctx by the [Middleware]
+// grants all of the required access rights.
+func Allowed(ctx context.Context, required ...models.AccessRight) bool {
+	info, ok := TokenFromContext(ctx)
+	return ok && models.IsSubset(required, info.Access)
+}
@@ -0,0 +1,122 @@
+package rs
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bingxueshuang/gnap/models"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/yaronf/httpsign"
+)
+
+// testKey generates an ed25519 key pair with the public part as
+// a client key using httpsig proof.
+func testKey(t *testing.T) (models.ClientKey, ed25519.PrivateKey) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := jwk.FromRaw(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return models.ClientKey{Proof: models.ProofHTTPSig, JWK: data}, priv
+}
+
+func TestMiddleware_Handler(t *testing.T) {
+	key, priv := testKey(t)
+	_, other := testKey(t)
+	access := []models.AccessRight{{Type: "photo-api", Actions: []string{"read"}}}
+	tokens := map[string]TokenInfo{
+		"bearer": {Access: access, Flags: []models.TokenFlag{models.FlagBearer}},
+		"bound":  {Access: access, Key: &key},
+		"nokey":  {Access: access},
+		"keyed":  {Access: access, Key: &key, Flags: []models.TokenFlag{models.FlagBearer}},
+	}
+	validator := ValidatorFunc(func(ctx context.Context, token string, method models.ProofMethod) (TokenInfo, error) {
+		info, ok := tokens[token]
+		if !ok {
+			return TokenInfo{}, ErrInvalidToken
+		}
+		return info, nil
+	})
+	sign := func(priv ed25519.PrivateKey) func(*http.Request) {
+		return func(req *http.Request) {
+			fields := httpsign.Headers("@method", "@target-uri", "authorization")
+			signer, err := httpsign.NewEd25519Signer("client-key", priv, nil, fields)
+			if err != nil {
+				t.Fatal(err)
+			}
+			input, sig, err := httpsign.SignRequest("sig1", *signer, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Signature-Input", input)
+			req.Header.Set("Signature", sig)
+		}
+	}
+	tests := []struct {
+		name   string
+		auth   string
+		sign   func(*http.Request)
+		status int
+	}{
+		{name: "bearer", auth: "GNAP bearer", status: http.StatusOK},
+		{name: "bound", auth: "GNAP bound", sign: sign(priv), status: http.StatusOK},
+		{name: "missing", status: http.StatusUnauthorized},
+		{name: "scheme", auth: "Bearer bearer", status: http.StatusUnauthorized},
+		{name: "unknown", auth: "GNAP unknown", status: http.StatusUnauthorized},
+		{name: "unsigned", auth: "GNAP bound", status: http.StatusUnauthorized},
+		{name: "wrong key", auth: "GNAP bound", sign: sign(other), status: http.StatusUnauthorized},
+		{name: "unbound", auth: "GNAP nokey", status: http.StatusUnauthorized},
+		{name: "bearer with key", auth: "GNAP keyed", sign: sign(priv), status: http.StatusUnauthorized},
+	}
+	m, err := NewMiddleware(validator, WithASURI("https://server.example.com/tx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := AccessFromContext(r.Context())
+		if len(got) != 1 || got[0].Type != "photo-api" {
+			t.Errorf("AccessFromContext() = %v, want %v", got, access)
+		}
+		if !Allowed(r.Context(), models.AccessRight{Type: "photo-api", Actions: []string{"read"}}) {
+			t.Errorf("Allowed() = false, want true")
+		}
+		if Allowed(r.Context(), models.AccessRight{Type: "photo-api", Actions: []string{"write"}}) {
+			t.Errorf("Allowed() = true, want false")
+		}
+	}))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "https://rs.example.com/photos", nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			if tt.sign != nil {
+				tt.sign(req)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("Middleware.Handler() status = %v, want %v", rec.Code, tt.status)
+			}
+			if rec.Code == http.StatusUnauthorized {
+				want := `GNAP as_uri="https://server.example.com/tx"`
+				if got := rec.Header().Get("WWW-Authenticate"); got != want {
+					t.Errorf("WWW-Authenticate = %v, want %v", got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package rs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bingxueshuang/gnap/models"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"golang.org/x/exp/slices"
+)
+
+// ErrInvalidToken is returned when the presented access token could not
+// be validated.
+var ErrInvalidToken = errors.New("invalid access token")
+
+// TokenInfo describes a validated access token.
+type TokenInfo struct {
+	Access     []models.AccessRight
+	Key        *models.ClientKey
+	Flags      []models.TokenFlag
+	ExpiresAt  time.Time
+	InstanceID string
+}
+
+// IsBearer reports whether the token is a bearer token, which
+// is presented without any key proof.
+func (info TokenInfo) IsBearer() bool {
+	return slices.Contains(info.Flags, models.FlagBearer)
+}
+
+// Validator validates the access token value presented to the RS. The
+// proof method is the one used by the request presenting the token, as
+// given by [proof.Method], and is empty for requests without key proof.
+type Validator interface {
+	Validate(ctx context.Context, token string, method models.ProofMethod) (TokenInfo, error)
+}
+
+// ValidatorFunc is an adapter to allow the use of ordinary
+// functions as [Validator].
+type ValidatorFunc func(ctx context.Context, token string, method models.ProofMethod) (TokenInfo, error)
+
+// Validate implements [Validator] interface.
+func (f ValidatorFunc) Validate(ctx context.Context, token string, method models.ProofMethod) (TokenInfo, error) {
+	return f(ctx, token, method)
+}
+
+// JWTValidator validates access tokens issued as JWTs signed by the AS.
+// Apart from the registered claims, the token carries the granted rights
+// in the "access" claim, the bound key in the "key" claim and the token
+// flags in the "flags" claim, using the same encoding as the token
+// response.
+type JWTValidator struct {
+	Keys     jwk.Set // keys of the AS used to verify the token
+	Issuer   string  // expected "iss" claim, if not empty
+	Audience string  // expected "aud" claim, if not empty
+}
+
+// jwtClaims are the private claims of GNAP access tokens as JWTs.
+type jwtClaims struct {
+	Access     []models.AccessRight `json:"access"`
+	Key        *models.ClientKey    `json:"key,omitempty"`
+	Flags      []models.TokenFlag   `json:"flags,omitempty"`
+	InstanceID string               `json:"instance_id,omitempty"`
+}
+
+// Validate implements [Validator] interface. The proof method is not
+// needed, as the key is checked by the [Middleware].
+func (v JWTValidator) Validate(ctx context.Context, token string, method models.ProofMethod) (TokenInfo, error) {
+	options := []jwt.ParseOption{
+		jwt.WithKeySet(v.Keys),
+		jwt.WithValidate(true),
+		jwt.WithContext(ctx),
+	}
+	if v.Issuer != "" {
+		options = append(options, jwt.WithIssuer(v.Issuer))
+	}
+	if v.Audience != "" {
+		options = append(options, jwt.WithAudience(v.Audience))
+	}
+	tok, err := jwt.ParseString(token, options...)
+	if err != nil {
+		return TokenInfo{}, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	data, err := json.Marshal(tok.PrivateClaims())
+	if err != nil {
+		return TokenInfo{}, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	var claims jwtClaims
+	err = json.Unmarshal(data, &claims)
+	if err != nil {
+		return TokenInfo{}, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	return TokenInfo{
+		Access:     claims.Access,
+		Key:        claims.Key,
+		Flags:      claims.Flags,
+		ExpiresAt:  tok.Expiration(),
+		InstanceID: claims.InstanceID,
+	}, nil
+}
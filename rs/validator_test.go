@@ -0,0 +1,80 @@
+package rs
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+func TestJWTValidator_Validate(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := jwk.FromRaw(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = key.Set(jwk.KeyIDKey, "as-key")
+	_ = key.Set(jwk.AlgorithmKey, jwa.EdDSA)
+	set := jwk.NewSet()
+	_ = set.AddKey(key)
+	signKey, err := jwk.FromRaw(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = signKey.Set(jwk.KeyIDKey, "as-key")
+	issue := func(exp time.Time) string {
+		tok, err := jwt.NewBuilder().
+			Issuer("https://server.example.com/").
+			Expiration(exp).
+			Claim("access", []any{"dolphin-metadata", map[string]any{
+				"type":    "photo-api",
+				"actions": []string{"read"},
+			}}).
+			Claim("flags", []string{"bearer"}).
+			Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := jwt.Sign(tok, jwt.WithKey(jwa.EdDSA, signKey))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "valid", token: issue(time.Now().Add(time.Hour))},
+		{name: "expired", token: issue(time.Now().Add(-time.Hour)), wantErr: true},
+		{name: "garbage", token: "not-a-jwt", wantErr: true},
+	}
+	v := JWTValidator{Keys: set, Issuer: "https://server.example.com/"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := v.Validate(context.Background(), tt.token, "")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("JWTValidator.Validate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(info.Access) != 2 || info.Access[0].Ref != "dolphin-metadata" || info.Access[1].Type != "photo-api" {
+				t.Errorf("JWTValidator.Validate() access = %v", info.Access)
+			}
+			if !info.IsBearer() {
+				t.Errorf("JWTValidator.Validate() flags = %v, want bearer", info.Flags)
+			}
+		})
+	}
+}
@@ -1,7 +1,6 @@
 // Package subject provides models for **Subject Identifiers for Security Event Tokens**
-// as defined in draft-ietf-secevent-subject-identifiers-16. See
-// https://datatracker.ietf.org/doc/draft-ietf-secevent-subject-indentifiers/ for
-// the latest draft version.
+// as defined in RFC 9493. See https://www.rfc-editor.org/rfc/rfc9493 for the
+// specification.
 
 // For creating a new Subject Identifier, use constructor functions matching:
 //
@@ -10,4 +9,9 @@
 // where `format` is a subject id format. For more complicated usages, you can directly
 // use [ID] as struct literal. For including list of identifiers in aliases format,
 // ID.NoAlias helper function can be used. See the example of [ID] type for more details.
+//
+// Subject identifiers are validated in [Strict] mode by default, following RFC 9493.
+// To accept identifiers from issuers implementing earlier drafts, such as phone
+// numbers without the leading "+" or formats that are not in the registry, switch
+// to [Lenient] mode with [SetDefaultMode] or validate explicitly with ID.ValidateMode.
 package subject // import "github.com/bingxueshuang/gnap/subject"
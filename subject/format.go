@@ -5,14 +5,22 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/bingxueshuang/gnap/internal/registry"
 	"golang.org/x/exp/slices"
 )
 
+// Errors returned when registering extension formats with [RegisterFormat].
+var (
+	ErrEmptyFormat       = registry.ErrEmpty
+	ErrAlreadyRegistered = registry.ErrRegistered
+	ErrStandardFormat    = registry.ErrStandard
+)
+
 // Format represents a subject identifier format as defined in
 // Security Event Identifier Format Registry.
 type Format string
 
-// Subject identifier formats defined in the registery.
+// Subject identifier formats defined in the registery of RFC 9493.
 const (
 	Account       Format = "account"
 	Email         Format = "email"
@@ -24,6 +32,33 @@ const (
 	Aliases       Format = "aliases"
 )
 
+// Valid reports whether the format is defined in the registry.
+func (f Format) Valid() bool {
+	return formats.Contains(f)
+}
+
+// Standard reports whether the format is defined by RFC 9493, rather
+// than registered as an extension.
+func (f Format) Standard() bool {
+	return formats.Standard(f)
+}
+
+// RegisterFormat adds an extension format to the registry, with the
+// function reporting whether an identifier of that format is valid.
+// Identifiers of extension formats can only use the members of [ID].
+func RegisterFormat(f Format, validate func(id NoAlias) bool) error {
+	if validate == nil {
+		return ErrInvalidFormat
+	}
+	return formats.Register(f, validate)
+}
+
+// UnregisterFormat removes an extension format from the registry.
+// Standard formats cannot be removed.
+func UnregisterFormat(f Format) error {
+	return formats.Unregister(f)
+}
+
 // Equal is simple equality comparison for [ID].
 func Equal(a, b ID) bool {
 	if a.Format != b.Format {
@@ -65,13 +100,17 @@ func EqualNoAlias(a, b NoAlias) bool {
 var EmailRegex = regexp.MustCompile(`^([!#-'*+/-9=?A-Z^-~-]+(\.[!#-'*+/-9=?A-Z^-~-]+)*|"([]!#-[^-~ \t]|(\\[\t -~]))+")@([!#-'*+/-9=?A-Z^-~-]+(\.[!#-'*+/-9=?A-Z^-~-]+)*|\[[\t -Z^-~]*])$`)
 
 // PhoneRegex according to [E.164].
-var PhoneRegex = regexp.MustCompile(`^\+?[1-9]\d{1,14}$`)
+var PhoneRegex = regexp.MustCompile(`^\+[1-9]\d{1,14}$`)
+
+// lenientPhoneRegex accepts [E.164] numbers without the leading "+",
+// as issued by draft-era issuers.
+var lenientPhoneRegex = regexp.MustCompile(`^\+?[1-9]\d{1,14}$`)
 
 // validator is used to validate subject identifer object according to format.
 type validator func(id NoAlias) bool
 
-// formatRegistry is a mapping from format identifiers to corresponding validator.
-var formatRegistry = map[Format]validator{
+// formats is the registry mapping format identifiers to corresponding validator.
+var formats = registry.New(map[Format]validator{
 	Account:       validateAccount,
 	Email:         validateEmail,
 	IssuerSubject: validateIssuerSubject,
@@ -80,7 +119,7 @@ var formatRegistry = map[Format]validator{
 	DID:           validateDID,
 	URI:           validateURI,
 	Aliases:       nil, // to prevent "aliases" format in NoAlias
-}
+})
 
 // validateAccount verifies acct URI as defined in [RFC7565].
 func validateAccount(id NoAlias) bool {
@@ -154,7 +193,7 @@ func validateURI(id NoAlias) bool {
 // validateAliases verifies subject identifier with aliases.
 // If the list of identifiers containes exact duplicates, then
 // it is considered as invalid.
-func validateAliases(id ID) bool {
+func validateAliases(id ID, mode Mode) bool {
 	visited := make([]NoAlias, 0, len(id.Identifiers))
 	for _, v := range id.Identifiers {
 		// exact duplicates
@@ -165,7 +204,7 @@ func validateAliases(id ID) bool {
 		}
 		visited = append(visited, v)
 		// invalid NoAlias
-		if v.Validate() != nil {
+		if v.ValidateMode(mode) != nil {
 			return false
 		}
 	}
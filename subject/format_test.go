@@ -1,6 +1,8 @@
 package subject_test
 
 import (
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/bingxueshuang/gnap/subject"
@@ -125,3 +127,30 @@ func TestEqual(t *testing.T) {
 		})
 	}
 }
+
+func TestRegisterFormat(t *testing.T) {
+	const format subject.Format = "example_employee_id"
+	id := subject.ID{Format: format, ID: "E-1234"}
+	if err := id.Validate(); err == nil {
+		t.Fatalf("ID.Validate() error = nil before registration")
+	}
+	err := subject.RegisterFormat(format, func(id subject.NoAlias) bool {
+		return strings.HasPrefix(id.ID, "E-")
+	})
+	if err != nil {
+		t.Fatalf("RegisterFormat() error = %v", err)
+	}
+	defer subject.UnregisterFormat(format)
+	if err := id.Validate(); err != nil {
+		t.Errorf("ID.Validate() error = %v", err)
+	}
+	if format.Standard() || !subject.Email.Standard() {
+		t.Errorf("Format.Standard() does not tell extension formats apart")
+	}
+	if err := subject.RegisterFormat(subject.Email, func(subject.NoAlias) bool { return true }); !errors.Is(err, subject.ErrAlreadyRegistered) {
+		t.Errorf("RegisterFormat() error = %v, want %v", err, subject.ErrAlreadyRegistered)
+	}
+	if err := subject.UnregisterFormat(subject.Aliases); !errors.Is(err, subject.ErrStandardFormat) {
+		t.Errorf("UnregisterFormat() error = %v, want %v", err, subject.ErrStandardFormat)
+	}
+}
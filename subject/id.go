@@ -143,28 +143,42 @@ type ID struct {
 	Identifiers []NoAlias `json:"identifiers,omitempty"`
 }
 
-// Validate checks if all fields of id are valid.
+// Validate checks if all fields of id are valid, according
+// to the [DefaultMode].
 func (id NoAlias) Validate() error {
-	validate := formatRegistry[id.Format]
+	return id.ValidateMode(DefaultMode())
+}
+
+// ValidateMode checks if all fields of id are valid according to mode.
+func (id NoAlias) ValidateMode(mode Mode) error {
+	validate, ok := formats.Lookup(id.Format)
+	if !ok && mode == Lenient && id.Format != "" {
+		return nil
+	}
 	if validate == nil {
 		return ErrInvalidFormat
 	}
 	if validate(id) {
 		return nil
 	}
+	if mode == Lenient && id.Format == PhoneNumber && lenientPhoneRegex.MatchString(id.Phone) {
+		return nil
+	}
 	return ErrInvalidSubjectID
 }
 
-// Validate checks if all fields of id are valid.
+// Validate checks if all fields of id are valid, according
+// to the [DefaultMode].
 func (id ID) Validate() error {
-	v, ok := formatRegistry[id.Format]
-	if !ok {
-		return ErrInvalidFormat
-	}
-	if v != nil {
-		return id.NoAlias().Validate()
+	return id.ValidateMode(DefaultMode())
+}
+
+// ValidateMode checks if all fields of id are valid according to mode.
+func (id ID) ValidateMode(mode Mode) error {
+	if id.Format != Aliases {
+		return id.NoAlias().ValidateMode(mode)
 	}
-	ok = validateAliases(id)
+	ok := validateAliases(id, mode)
 	if !ok {
 		return ErrInvalidSubjectID
 	}
@@ -0,0 +1,34 @@
+package subject
+
+import "sync/atomic"
+
+// Mode selects how strictly subject identifiers are validated.
+type Mode int32
+
+// Validation modes.
+const (
+	// Strict validates subject identifiers against RFC 9493. Phone
+	// numbers must carry the leading "+" of E.164 and the format must
+	// be defined in the registry.
+	Strict Mode = iota
+	// Lenient accepts subject identifiers issued by draft-era issuers.
+	// Phone numbers may omit the leading "+", and formats not defined in
+	// the registry are accepted without checking their members. Members
+	// of such formats that are not modeled by [ID] are not retained.
+	Lenient
+)
+
+// defaultMode is the mode used by Validate and UnmarshalJSON.
+var defaultMode atomic.Int32
+
+// SetDefaultMode sets the mode used by the Validate methods and, hence,
+// by json decoding of [ID] and [NoAlias]. The default mode is [Strict].
+// It is safe for concurrent use.
+func SetDefaultMode(mode Mode) {
+	defaultMode.Store(int32(mode))
+}
+
+// DefaultMode returns the mode set by [SetDefaultMode].
+func DefaultMode() Mode {
+	return Mode(defaultMode.Load())
+}
@@ -0,0 +1,93 @@
+package subject_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/bingxueshuang/gnap/subject"
+)
+
+func TestID_ValidateMode(t *testing.T) {
+	tests := []struct {
+		name        string
+		in          subject.ID
+		wantStrict  bool // if error is expected in strict mode
+		wantLenient bool // if error is expected in lenient mode
+	}{
+		{
+			name: "phone",
+			in:   subject.ID{Format: subject.PhoneNumber, Phone: "+12065550100"},
+		},
+		{
+			name:       "phone without plus",
+			in:         subject.ID{Format: subject.PhoneNumber, Phone: "12065550100"},
+			wantStrict: true,
+		},
+		{
+			name:        "phone malformed",
+			in:          subject.ID{Format: subject.PhoneNumber, Phone: "+0206555"},
+			wantStrict:  true,
+			wantLenient: true,
+		},
+		{
+			name:       "unregistered format",
+			in:         subject.ID{Format: "unregistered", Issuer: "https://issuer.example.com/"},
+			wantStrict: true,
+		},
+		{
+			name:        "empty format",
+			in:          subject.ID{Email: "user@example.com"},
+			wantStrict:  true,
+			wantLenient: true,
+		},
+		{
+			name: "aliases",
+			in: subject.ID{
+				Format: subject.Aliases,
+				Identifiers: []subject.NoAlias{
+					{Format: subject.Email, Email: "user@example.com"},
+					{Format: subject.PhoneNumber, Phone: "12065550100"},
+				},
+			},
+			wantStrict: true,
+		},
+		{
+			name: "nested aliases",
+			in: subject.ID{
+				Format:      subject.Aliases,
+				Identifiers: []subject.NoAlias{{Format: subject.Aliases}},
+			},
+			wantStrict:  true,
+			wantLenient: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.in.ValidateMode(subject.Strict); (err != nil) != tt.wantStrict {
+				t.Errorf("ID.ValidateMode(Strict) error = %v, wantErr %v", err, tt.wantStrict)
+			}
+			if err := tt.in.ValidateMode(subject.Lenient); (err != nil) != tt.wantLenient {
+				t.Errorf("ID.ValidateMode(Lenient) error = %v, wantErr %v", err, tt.wantLenient)
+			}
+		})
+	}
+}
+
+func TestSetDefaultMode(t *testing.T) {
+	data := []byte(`{"format": "phone_number", "phone_number": "12065550100"}`)
+	var id subject.ID
+	if err := json.Unmarshal(data, &id); err == nil {
+		t.Errorf("json.Unmarshal() in strict mode error = nil, want error")
+	}
+	subject.SetDefaultMode(subject.Lenient)
+	defer subject.SetDefaultMode(subject.Strict)
+	if got := subject.DefaultMode(); got != subject.Lenient {
+		t.Errorf("DefaultMode() = %v, want %v", got, subject.Lenient)
+	}
+	if err := json.Unmarshal(data, &id); err != nil {
+		t.Errorf("json.Unmarshal() in lenient mode error = %v", err)
+	}
+	if id.Phone != "12065550100" {
+		t.Errorf("ID.Phone = %v, want %v", id.Phone, "12065550100")
+	}
+}
@@ -16,7 +16,7 @@ type testcase struct {
 }
 
 // testvector is a list of test cases obtained from
-// the examples in the draft document.
+// the examples in RFC 9493.
 var testvector = []testcase{
 	// Account Identifier Format
 	{